@@ -0,0 +1,160 @@
+// Inspect or replay the swipes that ran out of retries.
+//
+//	dlq inspect [-n count]
+//	dlq replay [-n count]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/rmqconsumer"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/config"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/routing"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Settings of the dlq tool
+type Config struct {
+	config.RabbitMQ `yaml:",inline"`
+	ShardConfig     string `yaml:"shard_config" env:"SHARD_CONFIG" usage:"JSON shard map, must match the httpserver's"`
+	Count           int    `yaml:"n" env:"DLQ_COUNT" default:"10" usage:"max number of messages"`
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command := os.Args[1]
+	var cfg Config
+	if err := config.Load(&cfg, "dlq", os.Args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			usage()
+		}
+		fail("invalid config: %v", err)
+	}
+
+	conn, err := amqp.Dial(cfg.RabbitURL())
+	if err != nil {
+		fail("unable to make RabbitMQ connection to %s: %v", cfg.RabbitMQ, err)
+	}
+	defer conn.Close()
+	ch, err := conn.Channel()
+	if err != nil {
+		fail("unable to open channel: %v", err)
+	}
+	defer ch.Close()
+
+	switch command {
+	case "inspect":
+		err = inspect(ch, cfg.Count)
+	case "replay":
+		var router shard.Router
+		if router, err = shard.NewRouterFromFile(cfg.ShardConfig); err != nil {
+			fail("invalid shard config: %v", err)
+		}
+		err = replay(ch, router, cfg.Count)
+	default:
+		usage()
+	}
+	if err != nil {
+		fail("%s failed: %v", command, err)
+	}
+}
+
+// Print up to count dead letters without removing them from the queue
+func inspect(ch *amqp.Channel, count int) error {
+	var lastTag uint64
+	for i := 0; i < count; i++ {
+		d, ok, err := ch.Get(rmqconsumer.DeadLetterQueue, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		lastTag = d.DeliveryTag
+		fmt.Printf("%d\tretries=%v\t%s\n", i+1, d.Headers[rmqconsumer.RetryHeader], d.Body)
+	}
+	if lastTag == 0 {
+		fmt.Println("dead letter queue is empty")
+		return nil
+	}
+	return ch.Nack(lastTag, true, true) // Put them all back
+}
+
+// Publish up to count dead letters back to the swipes exchange with a fresh retry count.
+// Dead letters lost their original routing key when they were retried, so it's derived from the swipe again.
+// A dead letter that isn't a swipe is parked so it doesn't block the rest of the replay.
+// Each dead letter is only acked once the broker confirmed its copy, otherwise it goes back to the queue.
+func replay(ch *amqp.Channel, router shard.Router, count int) error {
+	if err := ch.Confirm(false); err != nil {
+		return fmt.Errorf("failed to put channel in confirm mode: %w", err)
+	}
+	if _, err := ch.QueueDeclare(rmqconsumer.ParkedQueue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare parked queue: %w", err)
+	}
+	replayed, parked := 0, 0
+	for i := 0; i < count; i++ {
+		d, ok, err := ch.Get(rmqconsumer.DeadLetterQueue, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		// Headers carry the trace context, replayed swipes only start over with their retry count
+		var exchange, key string
+		headers := make(amqp.Table, len(d.Headers))
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		var sr models.SwipeRequest
+		if err = json.Unmarshal(d.Body, &sr); err != nil {
+			fmt.Fprintf(os.Stderr, "parking bad swipe: %v\t%s\n", err, d.Body)
+			key = rmqconsumer.ParkedQueue
+		} else {
+			exchange, key = routing.Exchange, routing.Key(router, sr)
+			delete(headers, rmqconsumer.RetryHeader)
+		}
+		confirm, err := ch.PublishWithDeferredConfirmWithContext(context.Background(), exchange, key, false, false, amqp.Publishing{
+			ContentType: d.ContentType,
+			Headers:     headers,
+			Body:        d.Body,
+		})
+		if err != nil {
+			ch.Nack(d.DeliveryTag, false, true)
+			return err
+		}
+		if !confirm.Wait() {
+			ch.Nack(d.DeliveryTag, false, true)
+			return errors.New("broker did not confirm the replayed swipe")
+		}
+		if err = ch.Ack(d.DeliveryTag, false); err != nil {
+			return err
+		}
+		if exchange == "" {
+			parked++
+		} else {
+			replayed++
+		}
+	}
+	fmt.Printf("replayed %d swipes, parked %d bad swipes in %s\n", replayed, parked, rmqconsumer.ParkedQueue)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq inspect|replay [-n count] [-config file]")
+	os.Exit(2)
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,97 @@
+// Move users between DynamoDB shards after changing SHARD_CONFIG.
+//
+// Deploy the new SHARD_CONFIG with the old layout under "previous" to the httpserver and consumer first,
+// then move each old table. Remove "previous" from the config once every table is done.
+//
+// -backfill fixes rows from before likedList existed instead, on every table in SHARD_CONFIG.
+//
+//	migrate -table SwipeData5 [-checkpoint migrate-SwipeData5.json] [-page 100]
+//	migrate -backfill [-page 100]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/migrate"
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/config"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+)
+
+// Settings of the migrate tool
+type Config struct {
+	config.AWS  `yaml:",inline"`
+	ShardConfig string `yaml:"shard_config" env:"SHARD_CONFIG" required:"true" usage:"JSON shard map with the previous layout"`
+	Table       string `yaml:"table" usage:"the table to move users out of"`
+	Checkpoint  string `yaml:"checkpoint" usage:"checkpoint file (default migrate-{table}.json)"`
+	PageSize    int    `yaml:"page" default:"100" usage:"rows per scan page"`
+	Backfill    bool   `yaml:"backfill" usage:"copy matchList into likedList on old rows and keep only mutual matches"`
+}
+
+func (c *Config) Validate() error {
+	if c.Table == "" && !c.Backfill {
+		return errors.New("table is required")
+	}
+	return nil
+}
+
+func main() {
+	var cfg Config
+	if err := config.Load(&cfg, "migrate", os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fail("invalid config: %v", err)
+	}
+	if cfg.Checkpoint == "" {
+		cfg.Checkpoint = fmt.Sprintf("migrate-%s.json", cfg.Table)
+	}
+
+	shards, err := shard.NewRouterFromFile(cfg.ShardConfig)
+	if err != nil {
+		fail("invalid shard config: %v", err)
+	}
+	db, err := store.NewDatabaseClient(cfg.AWSRegion, shards)
+	if err != nil {
+		fail("unable to connect to database: %v", err)
+	}
+	if cfg.Backfill {
+		b := &migrate.Backfiller{
+			Client:   db.Client,
+			Router:   shards,
+			PageSize: int32(cfg.PageSize),
+		}
+		progress, err := b.Run(context.Background())
+		if err != nil {
+			fail("backfill stopped, run again to finish: %v", err)
+		}
+		fmt.Printf("done: scanned %d users, backfilled likes of %d, dropped %d one-sided matches\n",
+			progress.Scanned, progress.Liked, progress.Unmatched)
+		return
+	}
+	router, ok := db.Router.(*shard.MigratingRouter)
+	if !ok {
+		fail("SHARD_CONFIG has no previous layout to migrate from")
+	}
+
+	m := &migrate.Migrator{
+		Client:     db.Client,
+		Router:     router,
+		Checkpoint: cfg.Checkpoint,
+		PageSize:   int32(cfg.PageSize),
+	}
+	progress, err := m.Run(context.Background(), cfg.Table)
+	if err != nil {
+		fail("migration stopped, run again to resume: %v", err)
+	}
+	fmt.Printf("done: scanned %d users in %s, moved %d\n", progress.Scanned, cfg.Table, progress.Moved)
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/config"
+)
+
+// Settings of the consumer. Every setting can come from an env variable, the YAML file at CONFIG_FILE or -config,
+// or a flag named after its yaml key.
+type Config struct {
+	config.Logging  `yaml:",inline"`
+	config.RabbitMQ `yaml:",inline"`
+	config.AWS      `yaml:",inline"`
+	config.Tracing  `yaml:",inline"`
+	config.Metrics  `yaml:",inline"`
+
+	Port       string `yaml:"port" env:"PORT" default:"8080" usage:"port to serve /health and /metrics on"`
+	ConsumerId string `yaml:"consumer_id" env:"RAILWAY_REPLICA_ID" usage:"id of this replica in the metrics"`
+
+	DBBackend        string        `yaml:"db_backend" env:"DB_BACKEND" default:"dynamodb" oneof:"dynamodb,sqlite,postgres,memory" usage:"database backend, memory can't be read by the httpserver"`
+	SqlitePath       string        `yaml:"sqlite_path" env:"SQLITE_PATH" default:"twinder.db" usage:"path to the SQLite file"`
+	PostgresURL      string        `yaml:"postgres_url" env:"POSTGRES_URL" usage:"PostgreSQL connection string"`
+	SnapshotPath     string        `yaml:"snapshot_path" env:"SNAPSHOT_PATH" usage:"where the in-memory store is snapshotted, empty disables snapshots"`
+	SnapshotInterval time.Duration `yaml:"snapshot_interval" env:"SNAPSHOT_INTERVAL" default:"30s" usage:"how often the in-memory store is snapshotted"`
+	ShardConfig      string        `yaml:"shard_config" env:"SHARD_CONFIG" usage:"JSON shard map, must match the httpserver's"`
+
+	Queue      string   `yaml:"consumer_queue" env:"CONSUMER_QUEUE" usage:"durable queue shared with other consumers, empty for a temporary queue"`
+	QueueType  string   `yaml:"queue_type" env:"QUEUE_TYPE" default:"classic" oneof:"classic,quorum" usage:"type of the shared queue"`
+	Directions []string `yaml:"consumer_directions" env:"CONSUMER_DIRECTIONS" oneof:"left,right" usage:"swipe directions to bind to with a topic exchange, empty for all"`
+	Shards     []string `yaml:"consumer_shards" env:"CONSUMER_SHARDS" usage:"swiper tables to bind to with a topic exchange, empty for all"`
+
+	Prefetch          int           `yaml:"prefetch" env:"PREFETCH" default:"64" usage:"unacked swipes RabbitMQ sends ahead"`
+	Concurrency       int           `yaml:"concurrency" env:"CONCURRENCY" default:"50" usage:"swipes handled at once, also the max batch size"`
+	DedupTTL          time.Duration `yaml:"dedup_ttl" env:"DEDUP_TTL" default:"24h" usage:"how long the store remembers applied swipe ids to skip redeliveries"`
+	BatchWait         time.Duration `yaml:"batch_wait" env:"BATCH_WAIT" default:"10ms" usage:"how long a batch waits to fill up"`
+	CacheInvalidation bool          `yaml:"cache_invalidation" env:"CACHE_INVALIDATION" usage:"announce written userIds so httpservers can drop them from their caches"`
+}
+
+func (c *Config) Validate() error {
+	if err := c.ValidateMetrics(); err != nil {
+		return err
+	}
+	if c.QueueType == "quorum" && c.Queue == "" {
+		return errors.New("queue_type quorum needs a consumer_queue")
+	}
+	if c.DBBackend == "postgres" && c.PostgresURL == "" {
+		return errors.New("db_backend postgres needs a postgres_url")
+	}
+	if c.Prefetch <= 0 || c.Concurrency <= 0 {
+		return errors.New("prefetch and concurrency must be positive")
+	}
+	if c.SnapshotInterval <= 0 || c.DedupTTL <= 0 || c.BatchWait <= 0 {
+		return errors.New("snapshot_interval, dedup_ttl, and batch_wait must be positive")
+	}
+	return nil
+}
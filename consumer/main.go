@@ -1,38 +1,133 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/metrics"
 	"github.com/DennisPing/cs6650-twinder-a3/consumer/rmqconsumer"
 	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/config"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+	libmetrics "github.com/DennisPing/cs6650-twinder-a3/lib/metrics"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/routing"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
 )
 
 var zlog = logger.GetLogger()
 
 func main() {
-	store, err := store.NewDatabaseClient()
+	var cfg Config
+	if err := config.Load(&cfg, "consumer", os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		zlog.Fatal().Err(err).Msg("invalid config")
+	}
+	if err := cfg.SetLogLevel(); err != nil {
+		zlog.Fatal().Err(err).Msg("invalid config")
+	}
+
+	// Trace requests from the httpserver through RabbitMQ to the database
+	shutdownTracing, err := tracing.Setup(context.Background(), "consumer", tracing.Options{
+		Exporter: cfg.TraceExporter,
+		Endpoint: cfg.TraceEndpoint,
+	})
 	if err != nil {
-		zlog.Fatal().Err(err).Msg("unable to connect to DynamoDB")
+		zlog.Fatal().Err(err).Msg("unable to set up tracing")
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			zlog.Error().Err(err).Msg("failed to flush traces")
+		}
+	}()
 
-	conn, err := rmqconsumer.NewRmqConn()
+	// Initialize metrics client
+	var metricsClient metrics.Metrics
+	if cfg.MetricsBackend == "prometheus" {
+		metricsClient = metrics.NewPrometheusMetrics()
+	} else {
+		axiomClient, err := metrics.NewMetricsClient(cfg.ConsumerId, cfg.AxiomToken, cfg.AxiomDataset)
+		if err != nil {
+			zlog.Fatal().Err(err).Msg("unable to set up metrics")
+		}
+		metricsClient = axiomClient
+	}
+
+	router, err := shard.NewRouterFromFile(cfg.ShardConfig)
+	if err != nil {
+		zlog.Fatal().Err(err).Msg("invalid shard config")
+	}
+	store, err := store.NewStore(store.Options{
+		Backend:          cfg.DBBackend,
+		SqlitePath:       cfg.SqlitePath,
+		PostgresURL:      cfg.PostgresURL,
+		SnapshotPath:     cfg.SnapshotPath,
+		SnapshotInterval: cfg.SnapshotInterval,
+		Region:           cfg.AWSRegion,
+		Router:           router,
+		AppliedTTL:       cfg.DedupTTL,
+	})
 	if err != nil {
-		zlog.Fatal().Err(err).Msg("unable to make RabbitMQ connection")
+		zlog.Fatal().Err(err).Msg("unable to connect to database")
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	conn, err := rmqconsumer.NewRmqConn(cfg.RabbitURL())
+	if err != nil {
+		zlog.Fatal().Err(err).Stringer("rabbitmq", cfg.RabbitMQ).Msg("unable to make RabbitMQ connection")
 	}
 
-	cc, err := rmqconsumer.NewConsumerClient(conn, store)
+	cc, err := rmqconsumer.NewConsumerClient(conn, store, rmqconsumer.Options{
+		URL:          cfg.RabbitURL(),
+		ExchangeKind: cfg.ExchangeKind,
+		Bindings:     routing.Bindings(cfg.Directions, cfg.Shards),
+		Queue:        cfg.Queue,
+		QueueType:    cfg.QueueType,
+		Prefetch:     cfg.Prefetch,
+		Concurrency:  cfg.Concurrency,
+		BatchWait:    cfg.BatchWait,
+		Announce:     cfg.CacheInvalidation,
+		Metrics:      metricsClient,
+	})
 	if err != nil {
 		zlog.Fatal().Err(err).Msg("RabbitMQ client crashed")
 	}
 	defer cc.Close()
 
+	// Send the metrics in the background and serve /health and /metrics
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go libmetrics.Run(ctx, metricsClient, cfg.MetricsInterval)
+	server := metrics.NewServer(fmt.Sprintf(":%s", cfg.Port), metricsClient)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			zlog.Fatal().Err(err).Msg("HTTP server died")
+		}
+	}()
+
 	// Set up a signal handler for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	zlog.Info().Msg("shutting down gracefully...")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		zlog.Error().Err(err).Msg("failed to shutdown HTTP server gracefully")
+	}
 }
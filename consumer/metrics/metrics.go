@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	libmetrics "github.com/DennisPing/cs6650-twinder-a3/lib/metrics"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+)
+
+//go:generate mockery --name=Metrics --filename=mock_metrics.go
+type Metrics interface {
+	libmetrics.Metrics
+	RecordWrite(latency time.Duration, err error)
+	IncrementThrottle()
+	AddInFlight(delta int)
+}
+
+// Metrics client that sends to Axiom. The same metrics are also served to Prometheus at /metrics.
+type AxiomMetrics struct {
+	*libmetrics.Axiom
+	*PrometheusMetrics
+	ConsumerId string
+	Writes     uint64        // Successful writes
+	Errors     uint64        // Failed writes
+	Throttles  uint64        // Writes the store throttled
+	WriteTime  time.Duration // Total time spent writing
+	WriteMax   time.Duration
+	InFlight   int // Deliveries being handled right now
+	LastSent   time.Time
+	Mutex      sync.Mutex
+}
+
+// Create a new AxiomMetrics client which implements the Metrics and Exporter interfaces
+func NewMetricsClient(consumerId, apiToken, datasetName string) (*AxiomMetrics, error) {
+	client, err := libmetrics.NewAxiom(apiToken, datasetName)
+	if err != nil {
+		return nil, err
+	}
+	return &AxiomMetrics{
+		Axiom:             client,
+		PrometheusMetrics: NewPrometheusMetrics(),
+		ConsumerId:        consumerId,
+		LastSent:          time.Now(),
+	}, nil
+}
+
+// Record how long a write took and whether it failed
+func (m *AxiomMetrics) RecordWrite(latency time.Duration, err error) {
+	m.PrometheusMetrics.RecordWrite(latency, err)
+	m.Mutex.Lock()
+	if err != nil {
+		m.Errors++
+	} else {
+		m.Writes++
+	}
+	m.WriteTime += latency
+	if latency > m.WriteMax {
+		m.WriteMax = latency
+	}
+	m.Mutex.Unlock()
+}
+
+// Increment the throttled write count
+func (m *AxiomMetrics) IncrementThrottle() {
+	m.PrometheusMetrics.IncrementThrottle()
+	m.Mutex.Lock()
+	m.Throttles++
+	m.Mutex.Unlock()
+}
+
+// Add to the number of deliveries being handled, negative when they're done
+func (m *AxiomMetrics) AddInFlight(delta int) {
+	m.PrometheusMetrics.AddInFlight(delta)
+	m.Mutex.Lock()
+	m.InFlight += delta
+	m.Mutex.Unlock()
+}
+
+// Return the write stats since the last call and reset the counts
+func (m *AxiomMetrics) snapshot() models.ConsumerWriteThroughput {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+	now := time.Now()
+	stats := models.ConsumerWriteThroughput{
+		Time:              now.Format(time.RFC3339Nano),
+		ConsumerId:        m.ConsumerId,
+		Throughput:        m.Writes,
+		WriteLatencyMaxMs: m.WriteMax.Milliseconds(),
+		Errors:            m.Errors,
+		Throttles:         m.Throttles,
+		InFlight:          m.InFlight,
+	}
+	if elapsed := now.Sub(m.LastSent).Seconds(); elapsed > 0 {
+		stats.WritesPerSec = float64(m.Writes) / elapsed
+	}
+	if total := m.Writes + m.Errors; total > 0 {
+		stats.WriteLatencyAvgMs = float64(m.WriteTime) / float64(total) / float64(time.Millisecond)
+	}
+	m.Writes, m.Errors, m.Throttles, m.WriteTime, m.WriteMax = 0, 0, 0, 0, 0
+	m.LastSent = now
+	return stats
+}
+
+// Send the metrics over to Axiom
+func (m *AxiomMetrics) SendMetrics() error {
+	event, err := libmetrics.ToEvent(m.snapshot())
+	if err != nil {
+		return err
+	}
+	return m.Ingest(context.Background(), event)
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The Axiom event is the ConsumerWriteThroughput of everything since the last send
+func TestAxiomSnapshot(t *testing.T) {
+	m := &AxiomMetrics{PrometheusMetrics: NewPrometheusMetrics(), ConsumerId: "c1", LastSent: time.Now().Add(-time.Second)}
+	m.AddInFlight(2)
+	m.RecordWrite(10*time.Millisecond, nil)
+	m.RecordWrite(30*time.Millisecond, errors.New("throttled"))
+	m.IncrementThrottle()
+
+	stats := m.snapshot()
+	assert.Equal(t, "c1", stats.ConsumerId)
+	assert.Equal(t, uint64(1), stats.Throughput)
+	assert.InDelta(t, 1, stats.WritesPerSec, 0.1)
+	assert.Equal(t, 20.0, stats.WriteLatencyAvgMs)
+	assert.Equal(t, int64(30), stats.WriteLatencyMaxMs)
+	assert.Equal(t, uint64(1), stats.Errors)
+	assert.Equal(t, uint64(1), stats.Throttles)
+	assert.Equal(t, 2, stats.InFlight)
+
+	stats = m.snapshot() // The counts reset but the in-flight gauge doesn't
+	assert.Equal(t, uint64(0), stats.Throughput)
+	assert.Equal(t, uint64(0), stats.Errors)
+	assert.Equal(t, 2, stats.InFlight)
+}
@@ -0,0 +1,177 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Metrics is an autogenerated mock type for the Metrics type
+type Metrics struct {
+	mock.Mock
+}
+
+type Metrics_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Metrics) EXPECT() *Metrics_Expecter {
+	return &Metrics_Expecter{mock: &_m.Mock}
+}
+
+// AddInFlight provides a mock function with given fields: delta
+func (_m *Metrics) AddInFlight(delta int) {
+	_m.Called(delta)
+}
+
+// Metrics_AddInFlight_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddInFlight'
+type Metrics_AddInFlight_Call struct {
+	*mock.Call
+}
+
+// AddInFlight is a helper method to define mock.On call
+//   - delta int
+func (_e *Metrics_Expecter) AddInFlight(delta interface{}) *Metrics_AddInFlight_Call {
+	return &Metrics_AddInFlight_Call{Call: _e.mock.On("AddInFlight", delta)}
+}
+
+func (_c *Metrics_AddInFlight_Call) Run(run func(delta int)) *Metrics_AddInFlight_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int))
+	})
+	return _c
+}
+
+func (_c *Metrics_AddInFlight_Call) Return() *Metrics_AddInFlight_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_AddInFlight_Call) RunAndReturn(run func(int)) *Metrics_AddInFlight_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IncrementThrottle provides a mock function with given fields:
+func (_m *Metrics) IncrementThrottle() {
+	_m.Called()
+}
+
+// Metrics_IncrementThrottle_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IncrementThrottle'
+type Metrics_IncrementThrottle_Call struct {
+	*mock.Call
+}
+
+// IncrementThrottle is a helper method to define mock.On call
+func (_e *Metrics_Expecter) IncrementThrottle() *Metrics_IncrementThrottle_Call {
+	return &Metrics_IncrementThrottle_Call{Call: _e.mock.On("IncrementThrottle")}
+}
+
+func (_c *Metrics_IncrementThrottle_Call) Run(run func()) *Metrics_IncrementThrottle_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Metrics_IncrementThrottle_Call) Return() *Metrics_IncrementThrottle_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_IncrementThrottle_Call) RunAndReturn(run func()) *Metrics_IncrementThrottle_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecordWrite provides a mock function with given fields: latency, err
+func (_m *Metrics) RecordWrite(latency time.Duration, err error) {
+	_m.Called(latency, err)
+}
+
+// Metrics_RecordWrite_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordWrite'
+type Metrics_RecordWrite_Call struct {
+	*mock.Call
+}
+
+// RecordWrite is a helper method to define mock.On call
+//   - latency time.Duration
+//   - err error
+func (_e *Metrics_Expecter) RecordWrite(latency interface{}, err interface{}) *Metrics_RecordWrite_Call {
+	return &Metrics_RecordWrite_Call{Call: _e.mock.On("RecordWrite", latency, err)}
+}
+
+func (_c *Metrics_RecordWrite_Call) Run(run func(latency time.Duration, err error)) *Metrics_RecordWrite_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(time.Duration), args[1].(error))
+	})
+	return _c
+}
+
+func (_c *Metrics_RecordWrite_Call) Return() *Metrics_RecordWrite_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_RecordWrite_Call) RunAndReturn(run func(time.Duration, error)) *Metrics_RecordWrite_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendMetrics provides a mock function with given fields:
+func (_m *Metrics) SendMetrics() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Metrics_SendMetrics_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendMetrics'
+type Metrics_SendMetrics_Call struct {
+	*mock.Call
+}
+
+// SendMetrics is a helper method to define mock.On call
+func (_e *Metrics_Expecter) SendMetrics() *Metrics_SendMetrics_Call {
+	return &Metrics_SendMetrics_Call{Call: _e.mock.On("SendMetrics")}
+}
+
+func (_c *Metrics_SendMetrics_Call) Run(run func()) *Metrics_SendMetrics_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Metrics_SendMetrics_Call) Return(_a0 error) *Metrics_SendMetrics_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Metrics_SendMetrics_Call) RunAndReturn(run func() error) *Metrics_SendMetrics_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+type mockConstructorTestingTNewMetrics interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewMetrics creates a new instance of Metrics. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewMetrics(t mockConstructorTestingTNewMetrics) *Metrics {
+	mock := &Metrics{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"time"
+
+	libmetrics "github.com/DennisPing/cs6650-twinder-a3/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics client that exposes the metrics to a Prometheus scraper. Writes per second is rate(twinder_consumer_writes_total).
+type PrometheusMetrics struct {
+	*libmetrics.Prometheus
+	writes       prometheus.Counter
+	writeErrors  prometheus.Counter
+	throttles    prometheus.Counter
+	writeLatency prometheus.Histogram
+	inFlight     prometheus.Gauge
+}
+
+// Create a new PrometheusMetrics client which implements the Metrics and Exporter interfaces
+func NewPrometheusMetrics() *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		writes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "twinder_consumer_writes_total",
+			Help: "Swipes written to the store",
+		}),
+		writeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "twinder_consumer_write_errors_total",
+			Help: "Swipes the store failed to write, including throttled ones",
+		}),
+		throttles: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "twinder_consumer_write_throttles_total",
+			Help: "Swipes the store throttled",
+		}),
+		writeLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "twinder_consumer_write_duration_seconds",
+			Help:    "Time to write a swipe, including the wait for its batch",
+			Buckets: prometheus.DefBuckets,
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "twinder_consumer_in_flight_deliveries",
+			Help: "Deliveries being handled right now",
+		}),
+	}
+	m.Prometheus = libmetrics.NewPrometheus(m.writes, m.writeErrors, m.throttles, m.writeLatency, m.inFlight)
+	return m
+}
+
+// Record how long a write took and whether it failed
+func (m *PrometheusMetrics) RecordWrite(latency time.Duration, err error) {
+	m.writeLatency.Observe(latency.Seconds())
+	if err != nil {
+		m.writeErrors.Inc()
+	} else {
+		m.writes.Inc()
+	}
+}
+
+// Increment the throttled write count
+func (m *PrometheusMetrics) IncrementThrottle() {
+	m.throttles.Inc()
+}
+
+// Add to the number of deliveries being handled, negative when they're done
+func (m *PrometheusMetrics) AddInFlight(delta int) {
+	m.inFlight.Add(float64(delta))
+}
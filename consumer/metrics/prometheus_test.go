@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics()
+	handler := NewServer(":0", m).Handler
+
+	m.AddInFlight(3)
+	m.AddInFlight(-1)
+	m.RecordWrite(time.Millisecond, nil)
+	m.RecordWrite(time.Millisecond, nil)
+	m.RecordWrite(time.Millisecond, errors.New("throttled"))
+	m.IncrementThrottle()
+	assert.NoError(t, m.SendMetrics())
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+
+	for _, want := range []string{
+		`twinder_consumer_writes_total 2`,
+		`twinder_consumer_write_errors_total 1`,
+		`twinder_consumer_write_throttles_total 1`,
+		`twinder_consumer_write_duration_seconds_count 3`,
+		`twinder_consumer_in_flight_deliveries 2`,
+	} {
+		assert.Contains(t, string(body), want)
+	}
+}
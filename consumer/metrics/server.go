@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"net/http"
+
+	libmetrics "github.com/DennisPing/cs6650-twinder-a3/lib/metrics"
+)
+
+// Create the consumer's HTTP server. It serves GET /health, and GET /metrics if the client is an Exporter,
+// which both the Axiom and Prometheus clients are.
+func NewServer(addr string, m Metrics) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Hello world!"))
+	})
+	if exporter, ok := m.(libmetrics.Exporter); ok { // Scraped instead of sent
+		mux.Handle("/metrics", exporter.Handler())
+	}
+	return &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+}
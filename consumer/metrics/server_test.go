@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics Metrics
+		path    string
+		want    int
+	}{
+		{"health", &AxiomMetrics{PrometheusMetrics: NewPrometheusMetrics()}, "/health", http.StatusOK},
+		{"axiom metrics", &AxiomMetrics{PrometheusMetrics: NewPrometheusMetrics()}, "/metrics", http.StatusOK},
+		{"prometheus metrics", NewPrometheusMetrics(), "/metrics", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			NewServer(":0", tt.metrics).Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
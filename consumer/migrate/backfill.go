@@ -0,0 +1,186 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/dynamo"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Fixes rows written before likedList existed, whose matchList holds every right swipe.
+// Both passes are idempotent and safe while the consumer runs, so a stopped backfill is just run again.
+type Backfiller struct {
+	Client   store.DynamoClienter // Interface
+	Router   shard.Router
+	PageSize int32
+}
+
+// Rows changed by a backfill
+type BackfillProgress struct {
+	Scanned   int `json:"scanned"`   // Rows scanned by the first pass
+	Liked     int `json:"liked"`     // Rows whose matchList was copied into likedList
+	Unmatched int `json:"unmatched"` // Matches dropped because they weren't mutual
+}
+
+// Copy matchList into likedList on every table, then drop the matches that aren't mutual.
+// The second pass checks the other user's likedList, so it only starts once every table had the first pass.
+func (b *Backfiller) Run(ctx context.Context) (BackfillProgress, error) {
+	var progress BackfillProgress
+	if _, ok := b.Router.(*shard.MigratingRouter); ok {
+		return progress, errors.New("finish moving users between shards before the backfill")
+	}
+	for _, table := range b.Router.Tables() {
+		err := b.scan(ctx, table, func(item models.DynamoUserStats) error {
+			progress.Scanned++
+			liked, err := b.copyLikes(ctx, table, item)
+			if liked {
+				progress.Liked++
+			}
+			return err
+		})
+		if err != nil {
+			return progress, err
+		}
+		zlog.Info().Str("table", table).Int("scanned", progress.Scanned).Int("liked", progress.Liked).Msg("backfilled likes")
+	}
+	for _, table := range b.Router.Tables() {
+		err := b.scan(ctx, table, func(item models.DynamoUserStats) error {
+			unmatched, err := b.dropOneSidedMatches(ctx, table, item)
+			progress.Unmatched += unmatched
+			return err
+		})
+		if err != nil {
+			return progress, err
+		}
+		zlog.Info().Str("table", table).Int("unmatched", progress.Unmatched).Msg("backfilled matches")
+	}
+	return progress, nil
+}
+
+// Internal method that calls fn for every row of table
+func (b *Backfiller) scan(ctx context.Context, table string, fn func(models.DynamoUserStats) error) error {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(table),
+		Limit:     aws.Int32(b.PageSize),
+	}
+	for {
+		page, err := b.Client.Scan(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		var items []models.DynamoUserStats
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return fmt.Errorf("failed to unmarshal items: %w", err)
+		}
+		for _, item := range items {
+			if err = fn(item); err != nil {
+				return err
+			}
+		}
+		if page.LastEvaluatedKey == nil {
+			return nil
+		}
+		input.ExclusiveStartKey = page.LastEvaluatedKey
+	}
+}
+
+// Add every match the user doesn't have in likedList yet. Every match is also a like, so this is right
+// for new rows too. Returns false if there was nothing to copy.
+func (b *Backfiller) copyLikes(ctx context.Context, table string, item models.DynamoUserStats) (bool, error) {
+	missing := difference(item.MatchList, item.LikedList)
+	if len(missing) == 0 {
+		return false, nil
+	}
+	_, err := b.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(table),
+		Key:              dynamo.UserKey(item.UserId),
+		UpdateExpression: aws.String("ADD likedList :liked"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":liked": numberSet(missing),
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to backfill likes of user %d: %w", item.UserId, err)
+	}
+	return true, nil
+}
+
+// Remove each match whose user never liked this user back. The removal is conditional on the other row,
+// so a like back that lands during the backfill keeps the match. Returns the number of matches removed.
+func (b *Backfiller) dropOneSidedMatches(ctx context.Context, table string, item models.DynamoUserStats) (int, error) {
+	unmatched := 0
+	for _, matchId := range item.MatchList {
+		matchTable := b.Router.Table(matchId)
+		other, err := dynamo.GetItem(ctx, b.Client, matchTable, matchId)
+		if err != nil {
+			return unmatched, err
+		}
+		if other != nil && contains(other.LikedList, item.UserId) {
+			continue // Mutual
+		}
+		_, err = b.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{
+					ConditionCheck: &types.ConditionCheck{
+						TableName:           aws.String(matchTable),
+						Key:                 dynamo.UserKey(matchId),
+						ConditionExpression: aws.String("NOT contains(likedList, :userId)"),
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":userId": &types.AttributeValueMemberN{Value: strconv.Itoa(item.UserId)},
+						},
+					},
+				},
+				{
+					Update: &types.Update{
+						TableName:        aws.String(table),
+						Key:              dynamo.UserKey(item.UserId),
+						UpdateExpression: aws.String("DELETE matchList :match"),
+						ExpressionAttributeValues: map[string]types.AttributeValue{
+							":match": numberSet([]int{matchId}),
+						},
+					},
+				},
+			},
+		})
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) && len(canceled.CancellationReasons) > 0 &&
+			aws.ToString(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+			continue // Liked back since the read
+		}
+		if err != nil {
+			return unmatched, fmt.Errorf("failed to drop match %d of user %d: %w", matchId, item.UserId, err)
+		}
+		unmatched++
+	}
+	return unmatched, nil
+}
+
+// The numbers in a that aren't in b
+func difference(a, b []int) []int {
+	var diff []int
+	for _, n := range a {
+		if !contains(b, n) {
+			diff = append(diff, n)
+		}
+	}
+	return diff
+}
+
+// Whether numbers has n
+func contains(numbers []int, n int) bool {
+	for _, m := range numbers {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// 1 liked 2 and 3 before likedList existed, only 2 liked 1 back
+func TestBackfillerRun(t *testing.T) {
+	ctx := context.Background()
+	router, err := shard.NewRouter(shard.Config{Ranges: []shard.Range{{Table: "SwipeData1"}}})
+	require.NoError(t, err)
+	mockDynamo := mocks.NewDynamoClienter(t)
+
+	legacy := map[string]types.AttributeValue{
+		"userId":    &types.AttributeValueMemberN{Value: "1"},
+		"matchList": &types.AttributeValueMemberNS{Value: []string{"2", "3"}},
+	}
+	liked := map[string]types.AttributeValue{
+		"userId":    &types.AttributeValueMemberN{Value: "1"},
+		"likedList": &types.AttributeValueMemberNS{Value: []string{"2", "3"}},
+		"matchList": &types.AttributeValueMemberNS{Value: []string{"2", "3"}},
+	}
+	mockDynamo.EXPECT().Scan(ctx, mock.Anything, mock.Anything).
+		Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{legacy}}, nil).Once()
+	mockDynamo.EXPECT().UpdateItem(ctx, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		set := input.ExpressionAttributeValues[":liked"].(*types.AttributeValueMemberNS)
+		return *input.UpdateExpression == "ADD likedList :liked" && assert.ObjectsAreEqual([]string{"2", "3"}, set.Value)
+	}), mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+
+	mockDynamo.EXPECT().Scan(ctx, mock.Anything, mock.Anything).
+		Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{liked}}, nil).Once()
+	mockDynamo.EXPECT().GetItem(ctx, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return input.Key["userId"].(*types.AttributeValueMemberN).Value == "2"
+	}), mock.Anything).Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+		"userId":    &types.AttributeValueMemberN{Value: "2"},
+		"likedList": &types.AttributeValueMemberNS{Value: []string{"1"}},
+	}}, nil).Once()
+	mockDynamo.EXPECT().GetItem(ctx, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return input.Key["userId"].(*types.AttributeValueMemberN).Value == "3"
+	}), mock.Anything).Return(&dynamodb.GetItemOutput{}, nil).Once()
+	mockDynamo.EXPECT().TransactWriteItems(ctx, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		check, update := input.TransactItems[0].ConditionCheck, input.TransactItems[1].Update
+		match := update.ExpressionAttributeValues[":match"].(*types.AttributeValueMemberNS)
+		return check.Key["userId"].(*types.AttributeValueMemberN).Value == "3" &&
+			*update.UpdateExpression == "DELETE matchList :match" && match.Value[0] == "3"
+	}), mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	b := &Backfiller{Client: mockDynamo, Router: router, PageSize: 100}
+	progress, err := b.Run(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, BackfillProgress{Scanned: 1, Liked: 1, Unmatched: 1}, progress)
+}
+
+// A like back that lands between the read and the removal keeps the match
+func TestBackfillerKeepsLateLikeBack(t *testing.T) {
+	ctx := context.Background()
+	router, err := shard.NewRouter(shard.Config{Ranges: []shard.Range{{Table: "SwipeData1"}}})
+	require.NoError(t, err)
+	mockDynamo := mocks.NewDynamoClienter(t)
+
+	mockDynamo.EXPECT().GetItem(ctx, mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{}, nil).Once()
+	mockDynamo.EXPECT().TransactWriteItems(ctx, mock.Anything, mock.Anything).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}},
+		}).Once()
+
+	b := &Backfiller{Client: mockDynamo, Router: router, PageSize: 100}
+	unmatched, err := b.dropOneSidedMatches(ctx, "SwipeData1", models.DynamoUserStats{UserId: 1, LikedList: []int{2}, MatchList: []int{2}})
+
+	assert.NoError(t, err)
+	assert.Zero(t, unmatched)
+}
+
+// Users still moving between shards could be read from the wrong table
+func TestBackfillerRefusesMigratingRouter(t *testing.T) {
+	b := &Backfiller{Client: mocks.NewDynamoClienter(t), Router: newTestRouter(t), PageSize: 100}
+	_, err := b.Run(context.Background())
+	assert.Error(t, err)
+}
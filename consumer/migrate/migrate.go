@@ -0,0 +1,198 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var zlog = logger.GetLogger()
+
+// Moves users out of a table into the table the current shard layout puts them in.
+// Reads merge both tables and the consumer writes to the new table while this runs.
+type Migrator struct {
+	Client     store.DynamoClienter // Interface
+	Router     *shard.MigratingRouter
+	Checkpoint string // Checkpoint file, empty means no resume
+	PageSize   int32
+}
+
+// Progress of a migration. Saved after every page so a stopped migration can resume.
+type Checkpoint struct {
+	Table   string `json:"table"`
+	LastKey int    `json:"lastKey"` // userId of the last scanned row, 0 means start from the beginning
+	Scanned int    `json:"scanned"`
+	Moved   int    `json:"moved"`
+	Done    bool   `json:"done"`
+}
+
+// Move every user in table that now belongs somewhere else. Resumes from the checkpoint file if it has one for table.
+func (m *Migrator) Run(ctx context.Context, table string) (Checkpoint, error) {
+	progress, err := m.loadCheckpoint(table)
+	if err != nil || progress.Done {
+		return progress, err
+	}
+	if progress.LastKey != 0 {
+		zlog.Info().Str("table", table).Int("lastKey", progress.LastKey).Msg("resuming migration")
+	}
+
+	for {
+		input := &dynamodb.ScanInput{
+			TableName: aws.String(table),
+			Limit:     aws.Int32(m.PageSize),
+		}
+		if progress.LastKey != 0 {
+			input.ExclusiveStartKey = map[string]types.AttributeValue{
+				"userId": &types.AttributeValueMemberN{Value: strconv.Itoa(progress.LastKey)},
+			}
+		}
+		page, err := m.Client.Scan(ctx, input)
+		if err != nil {
+			return progress, fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+
+		var items []models.DynamoUserStats
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return progress, fmt.Errorf("failed to unmarshal items: %w", err)
+		}
+		for _, item := range items {
+			target := m.Router.Table(item.UserId)
+			if target == table {
+				continue // Stays put
+			}
+			moved, err := m.moveUser(ctx, item, table, target)
+			if err != nil {
+				return progress, err
+			}
+			if moved {
+				progress.Moved++
+			}
+		}
+		progress.Scanned += len(items)
+
+		if page.LastEvaluatedKey == nil {
+			progress.Done = true
+		} else if err = attributevalue.Unmarshal(page.LastEvaluatedKey["userId"], &progress.LastKey); err != nil {
+			return progress, fmt.Errorf("failed to unmarshal last key: %w", err)
+		}
+		if err = m.saveCheckpoint(progress); err != nil {
+			return progress, err
+		}
+		zlog.Info().Str("table", table).Int("scanned", progress.Scanned).Int("moved", progress.Moved).Msg("migration progress")
+		if progress.Done {
+			return progress, nil
+		}
+	}
+}
+
+// Add the user's row to the target table and delete it from the source table in one transaction,
+// so a user is never counted twice. Returns false if the row was already moved.
+func (m *Migrator) moveUser(ctx context.Context, item models.DynamoUserStats, source, target string) (bool, error) {
+	key := map[string]types.AttributeValue{
+		"userId": &types.AttributeValueMemberN{Value: strconv.Itoa(item.UserId)},
+	}
+	update := "ADD numLikes :likes, numDislikes :dislikes"
+	values := map[string]types.AttributeValue{
+		":likes":    &types.AttributeValueMemberN{Value: strconv.Itoa(item.NumLikes)},
+		":dislikes": &types.AttributeValueMemberN{Value: strconv.Itoa(item.NumDislikes)},
+	}
+	if len(item.LikedList) > 0 { // Number sets can't be empty
+		update += ", likedList :liked"
+		values[":liked"] = numberSet(item.LikedList)
+	}
+	if len(item.MatchList) > 0 {
+		update += ", matchList :matches"
+		values[":matches"] = numberSet(item.MatchList)
+	}
+
+	_, err := m.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName:                 aws.String(target),
+					Key:                       key,
+					UpdateExpression:          aws.String(update),
+					ExpressionAttributeValues: values,
+				},
+			},
+			{
+				Delete: &types.Delete{
+					TableName:           aws.String(source),
+					Key:                 key,
+					ConditionExpression: aws.String("attribute_exists(userId)"),
+				},
+			},
+		},
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return false, nil // Moved by an earlier run
+			}
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to move user %d: %w", item.UserId, err)
+	}
+	return true, nil
+}
+
+// Internal method that reads the checkpoint for table. A missing file or another table's checkpoint means a fresh start.
+func (m *Migrator) loadCheckpoint(table string) (Checkpoint, error) {
+	fresh := Checkpoint{Table: table}
+	if m.Checkpoint == "" {
+		return fresh, nil
+	}
+	data, err := os.ReadFile(m.Checkpoint)
+	if errors.Is(err, os.ErrNotExist) {
+		return fresh, nil
+	}
+	if err != nil {
+		return fresh, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var progress Checkpoint
+	if err = json.Unmarshal(data, &progress); err != nil {
+		return fresh, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+	if progress.Table != table {
+		return fresh, nil
+	}
+	return progress, nil
+}
+
+// Internal method that writes the checkpoint file
+func (m *Migrator) saveCheckpoint(progress Checkpoint) error {
+	if m.Checkpoint == "" {
+		return nil
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	if err = os.WriteFile(m.Checkpoint, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Convert ints to a DynamoDB number set
+func numberSet(numbers []int) *types.AttributeValueMemberNS {
+	set := make([]string, len(numbers))
+	for i, n := range numbers {
+		set[i] = strconv.Itoa(n)
+	}
+	return &types.AttributeValueMemberNS{Value: set}
+}
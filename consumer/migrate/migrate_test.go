@@ -0,0 +1,109 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// SwipeData2 used to hold everyone above 1000, now 2001+ moves to SwipeData3
+func newTestRouter(t *testing.T) *shard.MigratingRouter {
+	router, err := shard.NewRouter(shard.Config{
+		Ranges: []shard.Range{{Max: 1000, Table: "SwipeData1"}, {Max: 2000, Table: "SwipeData2"}, {Table: "SwipeData3"}},
+		Previous: &shard.Config{
+			Ranges: []shard.Range{{Max: 1000, Table: "SwipeData1"}, {Table: "SwipeData2"}},
+		},
+	})
+	require.NoError(t, err)
+	return router.(*shard.MigratingRouter)
+}
+
+func userRow(userId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"userId":      &types.AttributeValueMemberN{Value: userId},
+		"numLikes":    &types.AttributeValueMemberN{Value: "2"},
+		"numDislikes": &types.AttributeValueMemberN{Value: "1"},
+		"likedList":   &types.AttributeValueMemberNS{Value: []string{"5", "6"}},
+	}
+}
+
+// Only users that belong to another table are moved, and progress is checkpointed after every page
+func TestMigratorRun(t *testing.T) {
+	ctx := context.Background()
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	mockDynamo := mocks.NewDynamoClienter(t)
+
+	mockDynamo.EXPECT().Scan(ctx, mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+		return input.ExclusiveStartKey == nil
+	}), mock.Anything).Return(&dynamodb.ScanOutput{
+		Items:            []map[string]types.AttributeValue{userRow("1500"), userRow("2500")},
+		LastEvaluatedKey: map[string]types.AttributeValue{"userId": &types.AttributeValueMemberN{Value: "2500"}},
+	}, nil).Once()
+	mockDynamo.EXPECT().Scan(ctx, mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+		return input.ExclusiveStartKey != nil
+	}), mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]types.AttributeValue{userRow("3000")},
+	}, nil).Once()
+	mockDynamo.EXPECT().TransactWriteItems(ctx, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		update, del := input.TransactItems[0].Update, input.TransactItems[1].Delete
+		return *update.TableName == "SwipeData3" && *del.TableName == "SwipeData2" &&
+			*update.UpdateExpression == "ADD numLikes :likes, numDislikes :dislikes, likedList :liked"
+	}), mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Twice()
+
+	m := &Migrator{
+		Client:     mockDynamo,
+		Router:     newTestRouter(t),
+		Checkpoint: checkpoint,
+		PageSize:   2,
+	}
+	progress, err := m.Run(ctx, "SwipeData2")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Checkpoint{Table: "SwipeData2", LastKey: 2500, Scanned: 3, Moved: 2, Done: true}, progress)
+
+	// A finished migration has nothing left to do
+	progress, err = m.Run(ctx, "SwipeData2")
+	assert.NoError(t, err)
+	assert.True(t, progress.Done)
+}
+
+// A stopped migration picks up after the last scanned user, and users moved by the earlier run are skipped
+func TestMigratorResume(t *testing.T) {
+	ctx := context.Background()
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	data, _ := json.Marshal(Checkpoint{Table: "SwipeData2", LastKey: 2500, Scanned: 2, Moved: 1})
+	require.NoError(t, os.WriteFile(checkpoint, data, 0o644))
+	mockDynamo := mocks.NewDynamoClienter(t)
+
+	mockDynamo.EXPECT().Scan(ctx, mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+		return input.ExclusiveStartKey["userId"].(*types.AttributeValueMemberN).Value == "2500"
+	}), mock.Anything).Return(&dynamodb.ScanOutput{
+		Items: []map[string]types.AttributeValue{userRow("3000")},
+	}, nil).Once()
+	mockDynamo.EXPECT().TransactWriteItems(ctx, mock.Anything, mock.Anything).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("None")}, {Code: aws.String("ConditionalCheckFailed")}},
+		}).Once()
+
+	m := &Migrator{
+		Client:     mockDynamo,
+		Router:     newTestRouter(t),
+		Checkpoint: checkpoint,
+		PageSize:   2,
+	}
+	progress, err := m.Run(ctx, "SwipeData2")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Checkpoint{Table: "SwipeData2", LastKey: 2500, Scanned: 3, Moved: 1, Done: true}, progress)
+}
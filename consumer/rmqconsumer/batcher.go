@@ -0,0 +1,129 @@
+package rmqconsumer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errBatcherClosed = errors.New("batcher is closed")
+
+// Collects swipes from concurrent HandleMessage calls and writes them to the store in one go
+type Batcher struct {
+	store    store.BatchStore
+	maxSize  int
+	maxWait  time.Duration
+	requests chan batchRequest
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// A swipe waiting to be written. The result of the batch write is sent back on the channel.
+type batchRequest struct {
+	swipe  store.Swipe
+	link   trace.Link // The batch write spans many traces, so it links to each of them
+	result chan error
+}
+
+// Create a new Batcher and start the flush loop. A batch is flushed when it reaches maxSize or after maxWait.
+func NewBatcher(store store.BatchStore, maxSize int, maxWait time.Duration) *Batcher {
+	b := &Batcher{
+		store:    store,
+		maxSize:  maxSize,
+		maxWait:  maxWait,
+		requests: make(chan batchRequest),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Submit a swipe and block until the batch containing it has been written. The batch span links to ctx's span.
+func (b *Batcher) Submit(ctx context.Context, swipe store.Swipe) error {
+	req := batchRequest{
+		swipe:  swipe,
+		link:   trace.LinkFromContext(ctx),
+		result: make(chan error, 1),
+	}
+	select {
+	case b.requests <- req:
+		return <-req.result
+	case <-b.quit:
+		return errBatcherClosed
+	}
+}
+
+// Stop accepting swipes and wait for the final batch to be written
+func (b *Batcher) Close() {
+	close(b.quit)
+	<-b.done
+}
+
+// The flush loop. Only one batch is ever in flight.
+func (b *Batcher) run() {
+	defer close(b.done)
+	batch := make([]batchRequest, 0, b.maxSize)
+	for {
+		// Block until the first swipe of the next batch arrives
+		select {
+		case req := <-b.requests:
+			batch = append(batch, req)
+		case <-b.quit:
+			return
+		}
+
+		timer := time.NewTimer(b.maxWait)
+	collect:
+		for len(batch) < b.maxSize {
+			select {
+			case req := <-b.requests:
+				batch = append(batch, req)
+			case <-timer.C:
+				break collect
+			case <-b.quit:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		b.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// Write the batch and report the result to every waiting caller
+func (b *Batcher) flush(batch []batchRequest) {
+	swipes := make([]store.Swipe, len(batch))
+	links := make([]trace.Link, 0, len(batch))
+	for i, req := range batch {
+		swipes[i] = req.swipe
+		if req.link.SpanContext.IsValid() {
+			links = append(links, req.link)
+		}
+	}
+	ctx, span := tracing.Start(context.Background(), "UpdateUserStatsBatch",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("batch.size", len(swipes))),
+	)
+	defer span.End()
+	err := b.store.UpdateUserStatsBatch(ctx, swipes)
+	tracing.RecordError(span, err)
+	if err != nil {
+		zlog.Error().Err(err).Int("size", len(swipes)).Msg("batch write failed")
+	}
+	var batchErr *store.BatchError
+	partial := errors.As(err, &batchErr)
+	for i, req := range batch {
+		if partial {
+			req.result <- batchErr.Errs[i] // nil if this swipe was applied
+		} else {
+			req.result <- err
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package rmqconsumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// A BatchStore that records every batch it receives
+type fakeBatchStore struct {
+	*mocks.Store
+	mu      sync.Mutex
+	batches [][]store.Swipe
+	err     error
+}
+
+func (f *fakeBatchStore) UpdateUserStatsBatch(ctx context.Context, swipes []store.Swipe) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, append([]store.Swipe(nil), swipes...))
+	return f.err
+}
+
+// Submit n swipes concurrently and return every result
+func submitAll(b *Batcher, n int) []error {
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = b.Submit(context.Background(), store.Swipe{UserId: i, Swipee: 1, Direction: "right"})
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestBatcherFlushesOnSize(t *testing.T) {
+	fake := &fakeBatchStore{}
+	b := NewBatcher(fake, 5, time.Hour) // never flush on time
+	defer b.Close()
+
+	errs := submitAll(b, 10)
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Len(t, fake.batches, 2)
+	for _, batch := range fake.batches {
+		assert.Len(t, batch, 5)
+	}
+}
+
+func TestBatcherFlushesOnTime(t *testing.T) {
+	fake := &fakeBatchStore{}
+	b := NewBatcher(fake, 100, 10*time.Millisecond)
+	defer b.Close()
+
+	errs := submitAll(b, 3)
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	total := 0
+	for _, batch := range fake.batches {
+		total += len(batch)
+	}
+	assert.Equal(t, 3, total)
+}
+
+func TestBatcherReportsError(t *testing.T) {
+	fake := &fakeBatchStore{err: errors.New("postgres died")}
+	b := NewBatcher(fake, 2, time.Hour)
+	defer b.Close()
+
+	errs := submitAll(b, 2)
+
+	for _, err := range errs {
+		assert.EqualError(t, err, "postgres died")
+	}
+}
+
+func TestBatcherClosed(t *testing.T) {
+	b := NewBatcher(&fakeBatchStore{}, 2, time.Millisecond)
+	b.Close()
+
+	err := b.Submit(context.Background(), store.Swipe{UserId: 1, Swipee: 2, Direction: "left"})
+	assert.ErrorIs(t, err, errBatcherClosed)
+}
+
+// Every caller gets the result of its own swipe when only part of the batch failed
+func TestBatcherReportsPartialError(t *testing.T) {
+	fake := &fakeBatchStore{err: &store.BatchError{Errs: map[int]error{1: errors.New("aws died")}}}
+	b := NewBatcher(fake, 2, time.Hour)
+	defer b.Close()
+
+	errs := submitAll(b, 2)
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			assert.EqualError(t, err, "aws died")
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+}
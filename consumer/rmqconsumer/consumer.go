@@ -4,43 +4,130 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strconv"
+	"time"
 
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/metrics"
 	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/routing"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
 	"github.com/wagslane/go-rabbitmq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var zlog = logger.GetLogger()
 
-// A rabbitmq consumer + dynamodb client
+// Defaults for the zero values in Options
+const (
+	prefetch    = 64
+	concurrency = 50
+	batchWait   = 10 * time.Millisecond
+)
+
+// How the consumer connects to the swipes exchange and handles swipes
+type Options struct {
+	URL          string        // amqp url of the broker, the dead letter queue is declared with it
+	ExchangeKind string        // fanout or topic, must match the httpserver's
+	Bindings     []string      // Routing keys to bind to, fanout exchanges ignore them
+	Queue        string        // Durable queue shared with other consumers, empty for a temporary queue
+	QueueType    string        // classic or quorum
+	Prefetch     int           // Unacked swipes RabbitMQ sends ahead
+	Concurrency  int           // Swipes handled at once, also the max batch size
+	BatchWait    time.Duration // How long a batch waits to fill up
+	Announce     bool          // Announce written userIds so httpservers can drop them from their caches
+
+	Metrics metrics.Metrics // Where write stats go, nil for none
+}
+
+// Internal method that fills in the defaults
+func (o Options) withDefaults() Options {
+	if o.ExchangeKind == "" {
+		o.ExchangeKind = "fanout"
+	}
+	if len(o.Bindings) == 0 {
+		o.Bindings = routing.Bindings(nil, nil)
+	}
+	if o.Prefetch <= 0 {
+		o.Prefetch = prefetch
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = concurrency
+	}
+	if o.BatchWait <= 0 {
+		o.BatchWait = batchWait
+	}
+	return o
+}
+
+// A rabbitmq consumer + database client
 type ConsumerClient struct {
-	Conn     *rabbitmq.Conn
-	Consumer *rabbitmq.Consumer
-	Store    *store.DatabaseClient
+	Conn      *rabbitmq.Conn
+	Consumer  *rabbitmq.Consumer
+	Publisher Publisher        // interface, republishes failed swipes
+	Store     store.Store      // interface
+	Metrics   metrics.Metrics  // interface, nil for no metrics
+	batcher   *Batcher         // nil if the store doesn't support batch writes
+	limiter   *AdaptiveLimiter // Slows down writes when the store is throttling
+	queue     string           // The queue we consume from, failed swipes are retried here
+	announce  bool             // Announce written userIds so httpservers can drop them from their caches
 }
 
-func NewConsumerClient(conn *rabbitmq.Conn, store *store.DatabaseClient) (*ConsumerClient, error) {
+func NewConsumerClient(conn *rabbitmq.Conn, db store.Store, opts Options) (*ConsumerClient, error) {
+	opts = opts.withDefaults()
+	queue, err := newQueueConfig(opts.Queue, opts.QueueType)
+	if err != nil {
+		return nil, err
+	}
+	if err := declareQueues(opts.URL); err != nil {
+		return nil, err
+	}
+	announce := opts.Announce
+	publisherOptions := []func(*rabbitmq.PublisherOptions){rabbitmq.WithPublisherOptionsLogging}
+	if announce {
+		publisherOptions = append(publisherOptions,
+			rabbitmq.WithPublisherOptionsExchangeDeclare,
+			rabbitmq.WithPublisherOptionsExchangeName("invalidations"),
+			rabbitmq.WithPublisherOptionsExchangeKind("fanout"),
+		)
+	}
+	publisher, err := rabbitmq.NewPublisher(conn, publisherOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rabbitmq publisher: %w", err)
+	}
+	// Registering a handler is what turns on confirm mode. Retries await their own confirms.
+	publisher.NotifyPublish(func(rabbitmq.Confirmation) {})
 	cc := &ConsumerClient{
-		Conn:  conn,
-		Store: store,
+		Conn:      conn,
+		Publisher: publisher,
+		Store:     db,
+		Metrics:   opts.Metrics,
+		limiter:   NewAdaptiveLimiter(opts.Concurrency, baseBackoff),
+		queue:     queue.name,
+		announce:  announce,
 	}
-	consumer, err := rabbitmq.NewConsumer(
-		conn,
-		cc.HandleMessage,
-		"",
+	if batchStore, ok := db.(store.BatchStore); ok {
+		cc.batcher = NewBatcher(batchStore, opts.Concurrency, opts.BatchWait) // A batch can't be larger than the number of handlers waiting on it
+	}
+	consumerOptions := []func(*rabbitmq.ConsumerOptions){
 		rabbitmq.WithConsumerOptionsLogging,
-		rabbitmq.WithConsumerOptionsRoutingKey(""), // Bind this default queue to default routing key
 		rabbitmq.WithConsumerOptionsExchangeDeclare,
-		rabbitmq.WithConsumerOptionsExchangeName("swipes"),
-		rabbitmq.WithConsumerOptionsExchangeKind("fanout"),
-		rabbitmq.WithConsumerOptionsQOSPrefetch(64),
-		rabbitmq.WithConsumerOptionsConcurrency(50),
-		rabbitmq.WithConsumerOptionsQueueAutoDelete, // Auto delete the queue upon disconnect
-	)
+		rabbitmq.WithConsumerOptionsExchangeName(routing.Exchange),
+		rabbitmq.WithConsumerOptionsExchangeKind(opts.ExchangeKind),
+		rabbitmq.WithConsumerOptionsQOSPrefetch(opts.Prefetch),
+		rabbitmq.WithConsumerOptionsConcurrency(opts.Concurrency),
+	}
+	consumerOptions = append(consumerOptions, queue.options()...)
+	for _, binding := range opts.Bindings { // Only the directions and shards we own, fanout exchanges ignore these
+		consumerOptions = append(consumerOptions, rabbitmq.WithConsumerOptionsRoutingKey(binding))
+	}
+	zlog.Info().Str("queue", queue.name).Bool("shared", queue.shared).Str("kind", opts.ExchangeKind).Strs("bindings", opts.Bindings).
+		Msg("binding to swipes exchange")
+	consumer, err := rabbitmq.NewConsumer(conn, cc.HandleMessage, cc.queue, consumerOptions...)
 	if err != nil {
+		publisher.Close()
 		return nil, fmt.Errorf("failed to create rabbitmq consumer: %w", err)
 	}
 	cc.Consumer = consumer
@@ -49,6 +136,16 @@ func NewConsumerClient(conn *rabbitmq.Conn, store *store.DatabaseClient) (*Consu
 
 func (cc *ConsumerClient) HandleMessage(d rabbitmq.Delivery) rabbitmq.Action {
 	zlog.Debug().Msg(string(d.Body))
+	// Continue the trace of the http request that published the swipe
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), d.Headers), "HandleMessage",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("messaging.system", "rabbitmq")),
+	)
+	defer span.End()
+	if cc.Metrics != nil {
+		cc.Metrics.AddInFlight(1)
+		defer cc.Metrics.AddInFlight(-1)
+	}
 
 	var reqBody models.SwipeRequest
 	err := json.Unmarshal(d.Body, &reqBody)
@@ -57,32 +154,93 @@ func (cc *ConsumerClient) HandleMessage(d rabbitmq.Delivery) rabbitmq.Action {
 		return rabbitmq.NackDiscard
 	}
 
+	// An invalid swipe would fail the whole batch, so reject it up front
+	if reqBody.Direction != "left" && reqBody.Direction != "right" {
+		zlog.Error().Interface("payload", reqBody).Msg("invalid swipe direction")
+		return rabbitmq.NackDiscard
+	}
+
+	span.SetAttributes(
+		attribute.String("swipe.id", reqBody.SwipeId),
+		attribute.String("swipe.direction", reqBody.Direction),
+	)
 	userId, _ := strconv.Atoi(reqBody.Swiper)
 	swipee, _ := strconv.Atoi(reqBody.Swipee)
-	err = cc.Store.UpdateUserStats(context.Background(), userId, swipee, reqBody.Direction)
+	swipe := store.Swipe{
+		SwipeId:   reqBody.SwipeId,
+		UserId:    userId,
+		Swipee:    swipee,
+		Direction: reqBody.Direction,
+		Comment:   reqBody.Comment,
+	}
+	cc.limiter.Acquire()
+	start := time.Now()
+	if cc.batcher != nil {
+		err = cc.batcher.Submit(ctx, swipe)
+	} else {
+		err = cc.Store.ApplySwipe(ctx, swipe)
+	}
+	kind := store.ClassifyError(err)
+	throttled := err != nil && kind == store.Throttled
+	cc.limiter.Release(throttled)
+	if cc.Metrics != nil {
+		cc.Metrics.RecordWrite(time.Since(start), err)
+		if throttled {
+			cc.Metrics.IncrementThrottle()
+		}
+	}
+	tracing.RecordError(span, err)
 	if err != nil {
-		zlog.Error().Err(err).Interface("payload", reqBody).Msg("consumer failed on UpdateUserStats")
+		zlog.Error().Err(err).Stringer("kind", kind).Interface("payload", reqBody).Msg("consumer failed on ApplySwipe")
+		if kind == store.Permanent {
+			return rabbitmq.NackDiscard // Straight to the dead letter queue
+		}
+		return cc.retry(d)
+	}
+	if cc.announce {
+		cc.announceWrite(swipe)
 	}
 	return rabbitmq.Ack
 }
 
-// Close the rabbitmq consumer and the underlying TCP connection
+// Tell the httpservers which users changed. A right swipe may have added a match to the swipee.
+func (cc *ConsumerClient) announceWrite(swipe store.Swipe) {
+	msg := models.CacheInvalidation{UserIds: []int{swipe.UserId}}
+	if swipe.Direction == "right" {
+		msg.UserIds = append(msg.UserIds, swipe.Swipee)
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		zlog.Error().Err(err).Msg("unable to marshal cache invalidation")
+		return
+	}
+	err = cc.Publisher.Publish(
+		body,
+		[]string{""},
+		rabbitmq.WithPublishOptionsContentType("application/json"),
+		rabbitmq.WithPublishOptionsExchange("invalidations"),
+	)
+	if err != nil {
+		zlog.Warn().Err(err).Msg("unable to announce cache invalidation") // The cache TTL still applies
+	}
+}
+
+// Close the rabbitmq consumer, the retry publisher, and the underlying TCP connection
 func (cc *ConsumerClient) Close() {
 	cc.Consumer.Close()
+	if cc.batcher != nil {
+		cc.batcher.Close()
+	}
+	if publisher, ok := cc.Publisher.(*rabbitmq.Publisher); ok {
+		publisher.Close()
+	}
 	cc.Conn.Close()
 }
 
-// Init a new RabbitMQ connection with the RabbitMQ host.
-func NewRmqConn() (*rabbitmq.Conn, error) {
-	host := os.Getenv("RABBITMQ_HOST")
-
-	if host == "" {
-		zlog.Fatal().Msg("you forgot to set the RABBITMQ_HOST env variable")
-	}
-
-	// Create a new connection to rabbitmq
+// Init a new RabbitMQ connection with the amqp url of the broker.
+func NewRmqConn(url string) (*rabbitmq.Conn, error) {
 	return rabbitmq.NewConn(
-		fmt.Sprintf("amqp://%s:%s@%s:5672", "guest", "guest", host),
+		url,
 		rabbitmq.WithConnectionOptionsLogging,
 	)
 }
@@ -0,0 +1,254 @@
+package rmqconsumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"unsafe"
+
+	mockMetrics "github.com/DennisPing/cs6650-twinder-a3/consumer/metrics/mocks"
+	mockRmq "github.com/DennisPing/cs6650-twinder-a3/consumer/rmqconsumer/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/aws/smithy-go"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/wagslane/go-rabbitmq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newDelivery(t *testing.T, sr models.SwipeRequest) rabbitmq.Delivery {
+	body, err := json.Marshal(sr)
+	assert.NoError(t, err)
+	return rabbitmq.Delivery{Delivery: amqp.Delivery{Body: body}}
+}
+
+// Match the swipe of userId on swipee, whatever its swipe id
+func swipeOf(userId, swipee int, direction string) interface{} {
+	return mock.MatchedBy(func(swipe store.Swipe) bool {
+		return swipe.UserId == userId && swipe.Swipee == swipee && swipe.Direction == direction
+	})
+}
+
+// Redelivered swipes reach the store with their swipe id, the store skips the ones it already applied
+func TestHandleMessagePassesSwipeId(t *testing.T) {
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().ApplySwipe(mock.Anything, mock.MatchedBy(func(swipe store.Swipe) bool {
+		return swipe.SwipeId == "abc"
+	})).Return(nil).Twice()
+	cc := &ConsumerClient{
+		Store:   mockStore,
+		limiter: NewAdaptiveLimiter(concurrency, 0),
+	}
+	d := newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5678", Direction: "right", SwipeId: "abc"})
+
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d))
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d))
+}
+
+// A failed swipe is republished with a bumped retry count and can be applied again
+func TestHandleMessageRetriesFailedSwipe(t *testing.T) {
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5678, "left")).Return(errors.New("aws died")).Once()
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5678, "left")).Return(nil).Once()
+	mockPublisher := mockRmq.NewPublisher(t)
+	var retried rabbitmq.PublishOptions
+	mockPublisher.EXPECT().PublishWithDeferredConfirmWithContext(mock.Anything, mock.Anything, []string{"swipes.consumer.test"}, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) {
+			for _, optionFunc := range optionFuncs {
+				optionFunc(&retried)
+			}
+		}).
+		Return(rabbitmq.PublisherConfirmation{ackedConfirm()}, nil).Once()
+	cc := &ConsumerClient{
+		Publisher: mockPublisher,
+		Store:     mockStore,
+		limiter:   NewAdaptiveLimiter(concurrency, 0),
+		queue:     "swipes.consumer.test",
+	}
+	d := newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5678", Direction: "left", SwipeId: "abc"})
+
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d))
+	assert.Equal(t, int32(1), retried.Headers[RetryHeader])
+	assert.Equal(t, "swipes.retry.100ms", retried.Exchange) // Held for the first backoff
+
+	d.Headers = amqp.Table(retried.Headers)
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d))
+}
+
+// A retry the broker didn't confirm is requeued instead of acked, so the swipe isn't lost
+func TestHandleMessageRetryNotConfirmed(t *testing.T) {
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5678, "left")).Return(errors.New("aws died")).Once()
+	mockPublisher := mockRmq.NewPublisher(t)
+	mockPublisher.EXPECT().PublishWithDeferredConfirmWithContext(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(rabbitmq.PublisherConfirmation{nil}, nil).Once() // Published before the channel was in confirm mode
+	cc := &ConsumerClient{
+		Publisher: mockPublisher,
+		Store:     mockStore,
+		limiter:   NewAdaptiveLimiter(concurrency, 0),
+	}
+	d := newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5678", Direction: "left", SwipeId: "abc"})
+
+	assert.Equal(t, rabbitmq.NackRequeue, cc.HandleMessage(d))
+}
+
+func TestAwaitConfirms(t *testing.T) {
+	tests := []struct {
+		name     string
+		confirms rabbitmq.PublisherConfirmation
+		err      error
+	}{
+		{name: "acked", confirms: rabbitmq.PublisherConfirmation{ackedConfirm()}},
+		{name: "not in confirm mode yet", confirms: rabbitmq.PublisherConfirmation{nil}, err: errNotConfirmed},
+		{name: "never acked", confirms: rabbitmq.PublisherConfirmation{&amqp.DeferredConfirmation{}}, err: errNotConfirmed},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			err := awaitConfirms(ctx, tc.confirms)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+// A confirm the broker already acked. amqp091 only makes them on a real channel.
+func ackedConfirm() *amqp.DeferredConfirmation {
+	confirm := &amqp.DeferredConfirmation{}
+	fields := reflect.ValueOf(confirm).Elem()
+	set := func(name string, value interface{}) {
+		field := fields.FieldByName(name)
+		reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(value))
+	}
+	done := make(chan struct{})
+	close(done)
+	set("done", done)
+	set("ack", true)
+	return confirm
+}
+
+// A swipe that keeps failing ends up in the dead letter queue
+func TestHandleMessageDeadLetters(t *testing.T) {
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5678, "left")).Return(errors.New("aws died")).Once()
+	cc := &ConsumerClient{
+		Store:   mockStore,
+		limiter: NewAdaptiveLimiter(concurrency, 0),
+	}
+	d := newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5678", Direction: "left", SwipeId: "abc"})
+	d.Headers = amqp.Table{RetryHeader: int32(maxRetries)}
+
+	assert.Equal(t, rabbitmq.NackDiscard, cc.HandleMessage(d))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, retryBackoff(baseBackoff, 0))
+	assert.Equal(t, 400*time.Millisecond, retryBackoff(baseBackoff, 2))
+	assert.Equal(t, maxBackoff, retryBackoff(baseBackoff, 10))
+	assert.Equal(t, maxBackoff, retryBackoff(baseBackoff, 100)) // overflow
+}
+
+// Throttled writes are retried and slow down the consumer, permanent errors are dead-lettered right away
+func TestHandleMessageClassifiesErrors(t *testing.T) {
+	throttled := &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException"}
+	permanent := &smithy.GenericAPIError{Code: "ValidationException"}
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5678, "right")).Return(fmt.Errorf("UpdateItem failed: %w", throttled)).Once()
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5679, "right")).Return(fmt.Errorf("UpdateItem failed: %w", permanent)).Once()
+	mockPublisher := mockRmq.NewPublisher(t)
+	mockPublisher.EXPECT().PublishWithDeferredConfirmWithContext(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(rabbitmq.PublisherConfirmation{ackedConfirm()}, nil).Once()
+	cc := &ConsumerClient{
+		Publisher: mockPublisher,
+		Store:     mockStore,
+		limiter:   NewAdaptiveLimiter(concurrency, 0),
+	}
+
+	d := newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5678", Direction: "right", SwipeId: "abc"})
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d)) // republished
+	assert.Equal(t, concurrency/2, cc.limiter.Limit())
+
+	d = newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5679", Direction: "right", SwipeId: "def"})
+	assert.Equal(t, rabbitmq.NackDiscard, cc.HandleMessage(d))
+}
+
+// Every write is timed, and throttled writes are counted
+func TestHandleMessageRecordsMetrics(t *testing.T) {
+	throttled := &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException"}
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5678, "right")).Return(nil).Once()
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5679, "right")).Return(fmt.Errorf("UpdateItem failed: %w", throttled)).Once()
+	mockPublisher := mockRmq.NewPublisher(t)
+	mockPublisher.EXPECT().PublishWithDeferredConfirmWithContext(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(rabbitmq.PublisherConfirmation{ackedConfirm()}, nil).Once()
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().AddInFlight(1).Times(2)
+	mockMetrics.EXPECT().AddInFlight(-1).Times(2)
+	mockMetrics.EXPECT().RecordWrite(mock.Anything, nil).Once()
+	mockMetrics.EXPECT().RecordWrite(mock.Anything, mock.MatchedBy(func(err error) bool { return err != nil })).Once()
+	mockMetrics.EXPECT().IncrementThrottle().Once()
+	cc := &ConsumerClient{
+		Publisher: mockPublisher,
+		Store:     mockStore,
+		Metrics:   mockMetrics,
+		limiter:   NewAdaptiveLimiter(concurrency, 0),
+	}
+
+	d := newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5678", Direction: "right", SwipeId: "abc"})
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d))
+	d = newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5679", Direction: "right", SwipeId: "def"})
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d)) // republished
+}
+
+// A written right swipe tells the httpservers to drop both users from their caches
+func TestHandleMessageAnnouncesWrite(t *testing.T) {
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().ApplySwipe(mock.Anything, swipeOf(1234, 5678, "right")).Return(nil).Once()
+	mockPublisher := mockRmq.NewPublisher(t)
+	mockPublisher.EXPECT().Publish(mock.MatchedBy(func(body []byte) bool {
+		var msg models.CacheInvalidation
+		return json.Unmarshal(body, &msg) == nil && assert.Equal(t, []int{1234, 5678}, msg.UserIds)
+	}), []string{""}, mock.Anything, mock.Anything).Return(nil).Once()
+	cc := &ConsumerClient{
+		Publisher: mockPublisher,
+		Store:     mockStore,
+		limiter:   NewAdaptiveLimiter(concurrency, 0),
+		announce:  true,
+	}
+	d := newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5678", Direction: "right", SwipeId: "abc"})
+
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d))
+}
+
+// The swipe is written in the trace of the http request that published it
+func TestHandleMessageContinuesTrace(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagator) })
+
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().ApplySwipe(mock.MatchedBy(func(ctx context.Context) bool {
+		return trace.SpanContextFromContext(ctx).TraceID().String() == "4bf92f3577b34da6a3ce929d0e0e4736"
+	}), swipeOf(1234, 5678, "right")).Return(nil).Once()
+	cc := &ConsumerClient{
+		Store:   mockStore,
+		limiter: NewAdaptiveLimiter(concurrency, 0),
+	}
+	d := newDelivery(t, models.SwipeRequest{Swiper: "1234", Swipee: "5678", Direction: "right", SwipeId: "abc"})
+	d.Headers = amqp.Table{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+
+	assert.Equal(t, rabbitmq.Ack, cc.HandleMessage(d))
+}
@@ -0,0 +1,63 @@
+package rmqconsumer
+
+import (
+	"sync"
+	"time"
+)
+
+// Limits how many swipes are written at once. The limit is halved when the store throttles us and
+// grows back by one after every successful write, so consumption slows down until the store keeps up.
+type AdaptiveLimiter struct {
+	mu           sync.Mutex
+	cond         *sync.Cond
+	max          int
+	limit        int
+	inFlight     int
+	cooldown     time.Duration // One throttling event fails many writes at once, so only halve once per cooldown
+	lastDecrease time.Time
+}
+
+// Create a new AdaptiveLimiter that starts at max
+func NewAdaptiveLimiter(max int, cooldown time.Duration) *AdaptiveLimiter {
+	l := &AdaptiveLimiter{
+		max:      max,
+		limit:    max,
+		cooldown: cooldown,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Block until a write is allowed
+func (l *AdaptiveLimiter) Acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+}
+
+// Finish a write and adjust the limit based on whether it was throttled
+func (l *AdaptiveLimiter) Release(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if throttled {
+		if now := time.Now(); now.Sub(l.lastDecrease) >= l.cooldown && l.limit > 1 {
+			l.limit /= 2
+			l.lastDecrease = now
+			zlog.Warn().Int("limit", l.limit).Msg("store is throttling, slowing down")
+		}
+	} else if l.limit < l.max {
+		l.limit++
+	}
+	l.cond.Broadcast()
+}
+
+// The current number of concurrent writes allowed
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
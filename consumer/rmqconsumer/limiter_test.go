@@ -0,0 +1,55 @@
+package rmqconsumer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The limit is halved on throttling and grows back one write at a time
+func TestAdaptiveLimiterAIMD(t *testing.T) {
+	l := NewAdaptiveLimiter(8, 0)
+
+	l.Acquire()
+	l.Release(true)
+	assert.Equal(t, 4, l.Limit())
+	l.Acquire()
+	l.Release(true)
+	assert.Equal(t, 2, l.Limit())
+
+	for i := 0; i < 10; i++ {
+		l.Acquire()
+		l.Release(false)
+	}
+	assert.Equal(t, 8, l.Limit()) // capped at max
+}
+
+// One throttling event that fails many writes only halves the limit once
+func TestAdaptiveLimiterCooldown(t *testing.T) {
+	l := NewAdaptiveLimiter(8, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		l.Acquire()
+		l.Release(true)
+	}
+	assert.Equal(t, 4, l.Limit())
+}
+
+// Writes block while the limit is reached
+func TestAdaptiveLimiterBlocks(t *testing.T) {
+	l := NewAdaptiveLimiter(1, 0)
+	l.Acquire()
+
+	var acquired atomic.Bool
+	go func() {
+		l.Acquire()
+		acquired.Store(true)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, acquired.Load())
+
+	l.Release(false)
+	assert.Eventually(t, acquired.Load, time.Second, time.Millisecond)
+}
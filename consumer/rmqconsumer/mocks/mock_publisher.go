@@ -0,0 +1,167 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	rabbitmq "github.com/wagslane/go-rabbitmq"
+)
+
+// Publisher is an autogenerated mock type for the Publisher type
+type Publisher struct {
+	mock.Mock
+}
+
+type Publisher_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Publisher) EXPECT() *Publisher_Expecter {
+	return &Publisher_Expecter{mock: &_m.Mock}
+}
+
+// Publish provides a mock function with given fields: data, routingKeys, optionFuncs
+func (_m *Publisher) Publish(data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) error {
+	_va := make([]interface{}, len(optionFuncs))
+	for _i := range optionFuncs {
+		_va[_i] = optionFuncs[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, data, routingKeys)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]byte, []string, ...func(*rabbitmq.PublishOptions)) error); ok {
+		r0 = rf(data, routingKeys, optionFuncs...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Publisher_Publish_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Publish'
+type Publisher_Publish_Call struct {
+	*mock.Call
+}
+
+// Publish is a helper method to define mock.On call
+//   - data []byte
+//   - routingKeys []string
+//   - optionFuncs ...func(*rabbitmq.PublishOptions)
+func (_e *Publisher_Expecter) Publish(data interface{}, routingKeys interface{}, optionFuncs ...interface{}) *Publisher_Publish_Call {
+	return &Publisher_Publish_Call{Call: _e.mock.On("Publish",
+		append([]interface{}{data, routingKeys}, optionFuncs...)...)}
+}
+
+func (_c *Publisher_Publish_Call) Run(run func(data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions))) *Publisher_Publish_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]func(*rabbitmq.PublishOptions), len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(func(*rabbitmq.PublishOptions))
+			}
+		}
+		run(args[0].([]byte), args[1].([]string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Publisher_Publish_Call) Return(_a0 error) *Publisher_Publish_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Publisher_Publish_Call) RunAndReturn(run func([]byte, []string, ...func(*rabbitmq.PublishOptions)) error) *Publisher_Publish_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PublishWithDeferredConfirmWithContext provides a mock function with given fields: ctx, data, routingKeys, optionFuncs
+func (_m *Publisher) PublishWithDeferredConfirmWithContext(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) (rabbitmq.PublisherConfirmation, error) {
+	_va := make([]interface{}, len(optionFuncs))
+	for _i := range optionFuncs {
+		_va[_i] = optionFuncs[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, data, routingKeys)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 rabbitmq.PublisherConfirmation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, []string, ...func(*rabbitmq.PublishOptions)) (rabbitmq.PublisherConfirmation, error)); ok {
+		return rf(ctx, data, routingKeys, optionFuncs...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, []string, ...func(*rabbitmq.PublishOptions)) rabbitmq.PublisherConfirmation); ok {
+		r0 = rf(ctx, data, routingKeys, optionFuncs...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(rabbitmq.PublisherConfirmation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []byte, []string, ...func(*rabbitmq.PublishOptions)) error); ok {
+		r1 = rf(ctx, data, routingKeys, optionFuncs...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Publisher_PublishWithDeferredConfirmWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishWithDeferredConfirmWithContext'
+type Publisher_PublishWithDeferredConfirmWithContext_Call struct {
+	*mock.Call
+}
+
+// PublishWithDeferredConfirmWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - data []byte
+//   - routingKeys []string
+//   - optionFuncs ...func(*rabbitmq.PublishOptions)
+func (_e *Publisher_Expecter) PublishWithDeferredConfirmWithContext(ctx interface{}, data interface{}, routingKeys interface{}, optionFuncs ...interface{}) *Publisher_PublishWithDeferredConfirmWithContext_Call {
+	return &Publisher_PublishWithDeferredConfirmWithContext_Call{Call: _e.mock.On("PublishWithDeferredConfirmWithContext",
+		append([]interface{}{ctx, data, routingKeys}, optionFuncs...)...)}
+}
+
+func (_c *Publisher_PublishWithDeferredConfirmWithContext_Call) Run(run func(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions))) *Publisher_PublishWithDeferredConfirmWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]func(*rabbitmq.PublishOptions), len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(func(*rabbitmq.PublishOptions))
+			}
+		}
+		run(args[0].(context.Context), args[1].([]byte), args[2].([]string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Publisher_PublishWithDeferredConfirmWithContext_Call) Return(_a0 rabbitmq.PublisherConfirmation, _a1 error) *Publisher_PublishWithDeferredConfirmWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Publisher_PublishWithDeferredConfirmWithContext_Call) RunAndReturn(run func(context.Context, []byte, []string, ...func(*rabbitmq.PublishOptions)) (rabbitmq.PublisherConfirmation, error)) *Publisher_PublishWithDeferredConfirmWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+type mockConstructorTestingTNewPublisher interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewPublisher creates a new instance of Publisher. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewPublisher(t mockConstructorTestingTNewPublisher) *Publisher {
+	mock := &Publisher{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
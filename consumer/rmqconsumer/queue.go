@@ -0,0 +1,59 @@
+package rmqconsumer
+
+import (
+	"fmt"
+
+	"github.com/rs/xid"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+// How the consumer's queue is declared
+type queueConfig struct {
+	name   string
+	shared bool // Durable and shared with every consumer that uses the same name
+	quorum bool // Replicated quorum queue, only for shared queues
+}
+
+// Get the config of the queue called name. Without a name every consumer gets its own temporary queue, which gets
+// every swipe of a fanout exchange and is deleted when the consumer stops. With a name, consumers compete for the
+// swipes in a durable queue that outlives them.
+func newQueueConfig(name, queueType string) (queueConfig, error) {
+	cfg := queueConfig{
+		name:   name,
+		shared: name != "",
+	}
+	if !cfg.shared {
+		cfg.name = "swipes.consumer." + xid.New().String()
+	}
+	switch queueType {
+	case "", "classic":
+	case "quorum":
+		if !cfg.shared {
+			return cfg, fmt.Errorf("QUEUE_TYPE=quorum needs a CONSUMER_QUEUE")
+		}
+		cfg.quorum = true
+	default:
+		return cfg, fmt.Errorf("invalid QUEUE_TYPE: %s", queueType)
+	}
+	return cfg, nil
+}
+
+// The consumer options that declare the queue
+func (q queueConfig) options() []func(*rabbitmq.ConsumerOptions) {
+	args := rabbitmq.Table{
+		"x-dead-letter-exchange": DeadLetterExchange, // Rejected swipes go to the dead letter queue
+	}
+	if !q.shared {
+		return []func(*rabbitmq.ConsumerOptions){
+			rabbitmq.WithConsumerOptionsQueueAutoDelete, // Auto delete the queue upon disconnect
+			rabbitmq.WithConsumerOptionsQueueArgs(args),
+		}
+	}
+	if q.quorum {
+		args["x-queue-type"] = "quorum"
+	}
+	return []func(*rabbitmq.ConsumerOptions){
+		rabbitmq.WithConsumerOptionsQueueDurable,
+		rabbitmq.WithConsumerOptionsQueueArgs(args),
+	}
+}
@@ -0,0 +1,54 @@
+package rmqconsumer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+func TestNewQueueConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		queue     string
+		queueType string
+		shared    bool
+		quorum    bool
+		wantErr   bool
+	}{
+		{name: "temporary queue"},
+		{name: "shared classic queue", queue: "swipes.work", shared: true},
+		{name: "shared quorum queue", queue: "swipes.work", queueType: "quorum", shared: true, quorum: true},
+		{name: "quorum needs a name", queueType: "quorum", wantErr: true},
+		{name: "unknown type", queue: "swipes.work", queueType: "stream", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := newQueueConfig(tc.queue, tc.queueType)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.shared, cfg.shared)
+			assert.Equal(t, tc.quorum, cfg.quorum)
+			if tc.shared {
+				assert.Equal(t, tc.queue, cfg.name)
+			} else {
+				assert.True(t, strings.HasPrefix(cfg.name, "swipes.consumer."))
+			}
+
+			var options rabbitmq.ConsumerOptions
+			for _, option := range cfg.options() {
+				option(&options)
+			}
+			assert.Equal(t, tc.shared, options.QueueOptions.Durable)
+			assert.Equal(t, !tc.shared, options.QueueOptions.AutoDelete)
+			assert.Equal(t, DeadLetterExchange, options.QueueOptions.Args["x-dead-letter-exchange"])
+			if tc.quorum {
+				assert.Equal(t, "quorum", options.QueueOptions.Args["x-queue-type"])
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package rmqconsumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+const (
+	maxRetries     = 5
+	baseBackoff    = 100 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+	confirmTimeout = 5 * time.Second // How long the broker has to confirm a retry
+
+	RetryHeader        = "x-retry-count" // Number of times a swipe was retried
+	RetryPrefix        = "swipes.retry." // Retry exchanges and queues, one per backoff, eg. swipes.retry.100ms
+	DeadLetterExchange = "swipes.dlx"
+	DeadLetterQueue    = "swipes.dlq"
+	ParkedQueue        = "swipes.parked" // Dead letters that aren't swipes, set aside by a replay
+)
+
+var errNotConfirmed = errors.New("broker did not confirm the retry")
+
+//go:generate mockery --name=Publisher --filename=mock_publisher.go
+type Publisher interface {
+	Publish(data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) error
+	PublishWithDeferredConfirmWithContext(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) (rabbitmq.PublisherConfirmation, error)
+}
+
+// Park a failed swipe in the retry queue of its backoff with a bumped retry count. The retry queue holds it for the
+// backoff and then dead-letters it back to our queue, so the handler never sleeps. The swipe is only acked once the
+// broker confirms the retry. After maxRetries the swipe is rejected, which sends it to the dead letter queue.
+func (cc *ConsumerClient) retry(d rabbitmq.Delivery) rabbitmq.Action {
+	attempt := retryCount(d.Headers)
+	if attempt >= maxRetries {
+		zlog.Error().Int("attempts", attempt).Str("body", string(d.Body)).Msg("giving up on swipe, sending to dead letter queue")
+		return rabbitmq.NackDiscard
+	}
+
+	headers := rabbitmq.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+	headers[RetryHeader] = int32(attempt + 1)
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+	confirms, err := cc.Publisher.PublishWithDeferredConfirmWithContext(
+		ctx,
+		d.Body,
+		[]string{cc.queue}, // Kept by the retry queue when it dead-letters the swipe through the default exchange
+		rabbitmq.WithPublishOptionsContentType("application/json"),
+		rabbitmq.WithPublishOptionsHeaders(headers),
+		rabbitmq.WithPublishOptionsExchange(retryName(retryBackoff(baseBackoff, attempt))),
+	)
+	if err == nil {
+		err = awaitConfirms(ctx, confirms)
+	}
+	if err != nil {
+		zlog.Error().Err(err).Msg("unable to republish swipe for retry")
+		return rabbitmq.NackRequeue // Don't lose the swipe
+	}
+	return rabbitmq.Ack
+}
+
+// Wait for the broker to ack every publish. A nil confirmation means the channel wasn't in confirm mode yet.
+func awaitConfirms(ctx context.Context, confirms rabbitmq.PublisherConfirmation) error {
+	for _, confirm := range confirms {
+		if confirm == nil {
+			return errNotConfirmed
+		}
+		acked, err := confirm.WaitContext(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errNotConfirmed, err)
+		}
+		if !acked {
+			return errNotConfirmed
+		}
+	}
+	return nil
+}
+
+// The name of the retry exchange and queue that hold swipes for backoff
+func retryName(backoff time.Duration) string {
+	return RetryPrefix + backoff.String()
+}
+
+// Get the retry count of a message. Messages that were never retried have no header.
+func retryCount(headers amqp.Table) int {
+	switch count := headers[RetryHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	default:
+		return 0
+	}
+}
+
+// Exponential backoff starting at base, capped at maxBackoff
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	backoff := base
+	for i := 0; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// Declare the retry queues, the dead letter exchange, and the durable queue that collects swipes which ran out of retries
+func declareQueues(url string) error {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err = ch.ExchangeDeclare(DeadLetterExchange, "fanout", true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead letter exchange: %w", err)
+	}
+	if _, err = ch.QueueDeclare(DeadLetterQueue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead letter queue: %w", err)
+	}
+	if err = ch.QueueBind(DeadLetterQueue, "", DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind dead letter queue: %w", err)
+	}
+	return declareRetryQueues(ch)
+}
+
+// Declare a fanout exchange and queue for every backoff. A queue expires its swipes after the backoff and dead-letters
+// them to the default exchange, which routes them back to the consumer queue named by their routing key.
+func declareRetryQueues(ch *amqp.Channel) error {
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		backoff := retryBackoff(baseBackoff, attempt)
+		name := retryName(backoff)
+		if err := ch.ExchangeDeclare(name, "fanout", true, false, false, false, nil); err != nil {
+			return fmt.Errorf("failed to declare retry exchange: %w", err)
+		}
+		_, err := ch.QueueDeclare(name, true, false, false, false, amqp.Table{
+			"x-message-ttl":          backoff.Milliseconds(),
+			"x-dead-letter-exchange": "",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue: %w", err)
+		}
+		if err = ch.QueueBind(name, "", name, false, nil); err != nil {
+			return fmt.Errorf("failed to bind retry queue: %w", err)
+		}
+	}
+	return nil
+}
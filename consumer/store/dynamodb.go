@@ -2,92 +2,396 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
+	"github.com/DennisPing/cs6650-twinder-a3/lib/dynamo"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/rs/xid"
 )
 
 //go:generate mockery --name=DynamoClienter --filename=mock_database.go
 type DynamoClienter interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
 }
 
+const (
+	commentsTable = "SwipeComments" // Comments are partitioned by the recipient's userId so they don't need sharding
+	appliedTable  = "AppliedSwipes" // Swipe ids that were applied. DynamoDB deletes them after expiresAt, the table's TTL attribute.
+
+	maxTransactItems = 100 // Max items in one TransactWriteItems call
+)
+
 // Database client that communicates with DynamoDB via interface
 type DatabaseClient struct {
 	Client DynamoClienter // Interface
+	Router shard.Router   // Interface, nil means the default shard ranges
+
+	AppliedTTL time.Duration // How long applied swipe ids are remembered, zero means a day
 }
 
-// Create a new DatabaseClient that has a DynamoDB client
-func NewDatabaseClient() (*DatabaseClient, error) {
+// Create a new DatabaseClient that has a DynamoDB client for the tables in region
+func NewDatabaseClient(region string, router shard.Router) (*DatabaseClient, error) {
 	// LoadDefaultConfig automatically loads env variables and ~/.aws/credentials
 	cfg, err := config.LoadDefaultConfig(
 		context.Background(),
-		config.WithRegion("us-east-2"),
+		config.WithRegion(region),
 	)
 	if err != nil {
 		return nil, err
 	}
 	return &DatabaseClient{
 		Client: dynamodb.NewFromConfig(cfg),
+		Router: router,
 	}, nil
 }
 
-// Update a user's stats. If userId doesn't exist, then a new entry is created
-func (d *DatabaseClient) UpdateUserStats(ctx context.Context, userId, swipee int, swipeDir string) error {
-	tableName := getTableShard(userId)
+// Get the shard layout, the default shard ranges if there is no Router
+func (d *DatabaseClient) router() shard.Router {
+	if d.Router == nil {
+		return defaultRouter
+	}
+	return d.Router
+}
+
+// Get the DynamoDB table a user lives in
+func (d *DatabaseClient) table(userId int) string {
+	return d.router().Table(userId)
+}
+
+// Get user likes and dislikes. Returns found, user stats, and error.
+func (d *DatabaseClient) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	item, err := d.getItem(ctx, userId)
+	var userStats models.UserStats
+	if err != nil {
+		return false, userStats, err // some database error
+	}
+	if item != nil {
+		return true,
+			models.UserStats{
+				NumLikes:    item.NumLikes,
+				NumDislikes: item.NumDislikes,
+			},
+			nil
+	}
+	return false, userStats, nil // not found
+}
+
+// Get user matches list. Returns found, user matches, and error.
+func (d *DatabaseClient) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	item, err := d.getItem(ctx, userId)
+	var userMatches models.UserMatches
+	if err != nil {
+		return false, userMatches, err
+	}
+	if item != nil {
+		return true,
+			models.UserMatches{
+				MatchList: item.MatchList,
+			},
+			nil
+	}
+	return false, userMatches, nil // not found
+}
+
+// Apply a swipe. The stats, like, comment, and swipe id are written in one transaction, then the match is added if it's mutual.
+func (d *DatabaseClient) ApplySwipe(ctx context.Context, swipe Swipe) error {
+	err := d.UpdateUserStatsBatch(ctx, []Swipe{swipe})
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr.Errs[0]
+	}
+	return err
+}
+
+// Apply many swipes with a single transaction per user instead of one per swipe.
+// Users are written concurrently. If only some swipes fail, a *BatchError says which ones.
+func (d *DatabaseClient) UpdateUserStatsBatch(ctx context.Context, swipes []Swipe) error {
+	byUser := make(map[int][]int)   // userId -> positions in the batch
+	firstOf := make(map[string]int) // swipe id -> position of its first copy
+	duplicates := make(map[int]int) // position -> position of the first copy
+	for i, swipe := range swipes {
+		if swipe.Direction != "right" && swipe.Direction != "left" {
+			return fmt.Errorf("invalid swipe direction: %s", swipe.Direction)
+		}
+		if swipe.SwipeId != "" {
+			if first, ok := firstOf[swipe.SwipeId]; ok { // A redelivery that landed in the same batch
+				duplicates[i] = first
+				continue
+			}
+			firstOf[swipe.SwipeId] = i
+		}
+		byUser[swipe.UserId] = append(byUser[swipe.UserId], i)
+	}
+
+	batchErr := &BatchError{Errs: make(map[int]error)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	fail := func(i int, err error) {
+		mu.Lock()
+		batchErr.Errs[i] = err
+		mu.Unlock()
+	}
+
+	// One transaction per user. A failed transaction fails every swipe in it.
+	for userId, positions := range byUser {
+		wg.Add(1)
+		go func(userId int, positions []int) {
+			defer wg.Done()
+			if err := d.applyUserSwipes(ctx, userId, swipes, positions); err != nil {
+				for _, i := range positions {
+					fail(i, err)
+				}
+			}
+		}(userId, positions)
+	}
+	wg.Wait()
+
+	// Then the matches of the swipes that were applied, now or by an earlier delivery. A pair that liked
+	// each other in the same batch only needs one match check since both likes are already written.
+	checked := make(map[[2]int]bool)
+	for _, positions := range byUser {
+		for _, i := range positions {
+			swipe := swipes[i]
+			if _, failed := batchErr.Errs[i]; failed || swipe.Direction != "right" {
+				continue
+			}
+			pair := [2]int{swipe.UserId, swipe.Swipee}
+			if pair[0] > pair[1] {
+				pair[0], pair[1] = pair[1], pair[0]
+			}
+			if checked[pair] {
+				continue
+			}
+			checked[pair] = true
+			wg.Add(1)
+			go func(i int, swipe Swipe) {
+				defer wg.Done()
+				if err := dynamo.AddMatchIfMutual(ctx, d.Client, d.router(), swipe.UserId, swipe.Swipee); err != nil {
+					fail(i, err)
+				}
+			}(i, swipe)
+		}
+	}
+	wg.Wait()
+
+	for i, first := range duplicates {
+		if err, failed := batchErr.Errs[first]; failed {
+			batchErr.Errs[i] = err
+		}
+	}
+	if len(batchErr.Errs) > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+// Internal method that applies the swipes of one user, split into as few transactions as fit.
+// Swipes whose id is already in the applied table were written by an earlier delivery, so they are dropped and the rest retried.
+func (d *DatabaseClient) applyUserSwipes(ctx context.Context, userId int, swipes []Swipe, positions []int) error {
+	var chunks [][]Swipe
+	var chunk []Swipe
+	size := 1 // The stats update
+	for _, i := range positions {
+		n := transactItemsPerSwipe(swipes[i])
+		if len(chunk) > 0 && size+n > maxTransactItems {
+			chunks = append(chunks, chunk)
+			chunk, size = nil, 1
+		}
+		chunk = append(chunk, swipes[i])
+		size += n
+	}
+	chunks = append(chunks, chunk)
+
+	for _, chunk := range chunks {
+		for len(chunk) > 0 {
+			applied, err := d.applyChunk(ctx, userId, chunk)
+			if err != nil {
+				return err
+			}
+			if applied == nil { // Written
+				break
+			}
+			pending := make([]Swipe, 0, len(chunk)-len(applied))
+			for j, swipe := range chunk {
+				if !applied[j] {
+					pending = append(pending, swipe)
+				}
+			}
+			chunk = pending
+		}
+	}
+	return nil
+}
+
+// The transaction items a swipe needs besides the stats update, its swipe id and its comment
+func transactItemsPerSwipe(swipe Swipe) int {
+	n := 0
+	if swipe.SwipeId != "" {
+		n++
+	}
+	if swipe.Comment != "" {
+		n++
+	}
+	return n
+}
+
+// Internal method that writes a user's stats, likes, comments, and swipe ids in one transaction.
+// If the transaction was canceled only because some swipe ids were already applied, those swipes are returned and nothing was written.
+func (d *DatabaseClient) applyChunk(ctx context.Context, userId int, swipes []Swipe) (map[int]bool, error) {
+	update, err := d.statsUpdate(userId, swipes)
+	if err != nil {
+		return nil, err
+	}
+	items := []types.TransactWriteItem{{Update: update}}
+	markers := make(map[int]int) // item index -> position in swipes
+	expiresAt := strconv.FormatInt(time.Now().Add(appliedTTL(d.AppliedTTL)).Unix(), 10)
+	for j, swipe := range swipes {
+		if swipe.SwipeId != "" {
+			markers[len(items)] = j
+			items = append(items, types.TransactWriteItem{Put: &types.Put{
+				TableName: aws.String(appliedTable),
+				Item: map[string]types.AttributeValue{
+					"swipeId":   &types.AttributeValueMemberS{Value: swipe.SwipeId},
+					"expiresAt": &types.AttributeValueMemberN{Value: expiresAt},
+				},
+				ConditionExpression: aws.String("attribute_not_exists(swipeId)"),
+			}})
+		}
+		if swipe.Comment != "" {
+			item, err := commentItem(swipe)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, types.TransactWriteItem{Put: &types.Put{
+				TableName: aws.String(commentsTable),
+				Item:      item,
+			}})
+		}
+	}
+
+	_, span := tracing.StartDynamoDB(ctx, "TransactWriteItems", d.table(userId))
+	_, err = d.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	span.End()
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		applied := make(map[int]bool)
+		onlyApplied := true
+		for k, reason := range canceled.CancellationReasons {
+			code := aws.ToString(reason.Code)
+			j, isMarker := markers[k]
+			switch {
+			case code == "ConditionalCheckFailed" && isMarker:
+				applied[j] = true
+			case code != "" && code != "None":
+				onlyApplied = false
+			}
+		}
+		if onlyApplied && len(applied) > 0 {
+			return applied, nil
+		}
+	}
+	tracing.RecordError(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("TransactWriteItems failed: %w", err) // Keep the error chain for ClassifyError
+	}
+	return nil, nil
+}
+
+// Internal method that adds a user's swipes to their counts and likedList
+func (d *DatabaseClient) statsUpdate(userId int, swipes []Swipe) (*types.Update, error) {
+	var numLikes, numDislikes int
+	var liked []string
+	seen := make(map[int]bool)
+	for _, swipe := range swipes {
+		if swipe.Direction == "left" {
+			numDislikes++
+			continue
+		}
+		numLikes++
+		if !seen[swipe.Swipee] { // A number set can't have duplicates
+			seen[swipe.Swipee] = true
+			liked = append(liked, strconv.Itoa(swipe.Swipee))
+		}
+	}
+
 	var update expression.UpdateBuilder
-	switch swipeDir {
-	case "right":
-		update = update.
-			Add(expression.Name("numLikes"), expression.Value(1)).
-			Set(expression.Name("numDislikes"), expression.IfNotExists(expression.Name("numDislikes"), expression.Value(0))).
-			Add(expression.Name("matchList"), expression.Value(
-				&types.AttributeValueMemberNS{Value: []string{strconv.Itoa(swipee)}},
-			))
-	case "left":
-		update = update.
-			Add(expression.Name("numDislikes"), expression.Value(1)).
-			Set(expression.Name("numLikes"), expression.IfNotExists(expression.Name("numLikes"), expression.Value(0)))
-	default:
-		return fmt.Errorf("invalid swipe direction: %s", swipeDir)
+	if numLikes > 0 {
+		update = update.Add(expression.Name("numLikes"), expression.Value(numLikes))
+	} else {
+		update = update.Set(expression.Name("numLikes"), expression.IfNotExists(expression.Name("numLikes"), expression.Value(0)))
+	}
+	if numDislikes > 0 {
+		update = update.Add(expression.Name("numDislikes"), expression.Value(numDislikes))
+	} else {
+		update = update.Set(expression.Name("numDislikes"), expression.IfNotExists(expression.Name("numDislikes"), expression.Value(0)))
+	}
+	if len(liked) > 0 {
+		update = update.Add(expression.Name("likedList"), expression.Value(
+			&types.AttributeValueMemberNS{Value: liked},
+		))
 	}
 
 	expr, err := expression.NewBuilder().WithUpdate(update).Build()
 	if err != nil {
-		return fmt.Errorf("failed to build expression: %w", err)
+		return nil, fmt.Errorf("failed to build expression: %w", err)
 	}
-
-	// Execute the UpdateItem operation
-	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		Key: map[string]types.AttributeValue{
-			"userId": &types.AttributeValueMemberN{Value: strconv.Itoa(userId)},
-		},
-		TableName:                 &tableName,
+	return &types.Update{
+		TableName:                 aws.String(d.table(userId)),
+		Key:                       dynamo.UserKey(userId),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		UpdateExpression:          expr.Update(),
-		ReturnValues:              types.ReturnValueNone,
+	}, nil
+}
+
+// A comment in the swipee's inbox. A retried swipe overwrites its own comment instead of adding another.
+func commentItem(swipe Swipe) (map[string]types.AttributeValue, error) {
+	commentId, createdAt := newCommentId(swipe)
+	item, err := attributevalue.MarshalMap(&models.DynamoComment{
+		UserId:    swipe.Swipee,
+		CommentId: commentId,
+		From:      swipe.UserId,
+		Comment:   swipe.Comment,
+		Direction: swipe.Direction,
+		CreatedAt: createdAt.Format(time.RFC3339),
 	})
 	if err != nil {
-		return fmt.Errorf("UpdateItem failed: %w", err)
-	}
-
-	// if err != nil {
-	// 	// https://github.com/golang/go/issues/37625#issuecomment-594033043
-	// 	var opErr *smithy.OperationError
-	// 	if errors.As(err, &opErr) {
-	// 		switch opErr.Unwrap().(type) {
-	// 		case *types.ProvisionedThroughputExceededException:
-	// 			return fmt.Errorf("update rate too high: %w", err)
-	// 		default:
-	// 			return fmt.Errorf("operation error: %w", err)
-	// 		}
-	// 	}
-	// 	return fmt.Errorf("unexpected error: %w", err)
-	// }
-	return nil
+		return nil, fmt.Errorf("failed to marshal comment: %w", err)
+	}
+	return item, nil
+}
+
+// Internal method that gets the entire row from DynamoDB, from both tables while a user is being moved
+func (d *DatabaseClient) getItem(ctx context.Context, userId int) (*models.DynamoUserStats, error) {
+	return dynamo.GetUserItem(ctx, d.Client, d.router(), userId)
+}
+
+// The sort key of a comment, the unix nano time it was made followed by its swipe id.
+// Swipe ids hold the time the httpserver accepted the swipe, so a retry gets the same key.
+// Swipes without an id fall back to the current time and the swiper's userId.
+func newCommentId(swipe Swipe) (string, time.Time) {
+	if id, err := xid.FromString(swipe.SwipeId); err == nil {
+		createdAt := id.Time().UTC()
+		return fmt.Sprintf("%019d#%s", createdAt.UnixNano(), swipe.SwipeId), createdAt
+	}
+	now := time.Now().UTC()
+	return fmt.Sprintf("%019d#%d", now.UnixNano(), swipe.UserId), now
 }
@@ -1,34 +1,82 @@
-package store
+package store_test
 
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/DennisPing/cs6650-twinder-a3/consumer/store"
 	"github.com/DennisPing/cs6650-twinder-a3/consumer/store/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/rs/xid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-// Update user stats happy path
-func TestUpdateUserStats(t *testing.T) {
+// Get user matches happy path
+func TestGetMatches(t *testing.T) {
+	ctx := context.Background()
+	mockDynamo := mocks.NewDynamoClienter(t)
+
+	mockDynamo.EXPECT().GetItem(ctx, mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{
+		Item: map[string]types.AttributeValue{
+			"matchList": &types.AttributeValueMemberNS{Value: []string{"5678"}},
+		},
+	}, nil)
+	databaseClient := store.DatabaseClient{
+		Client: mockDynamo,
+	}
+	found, matches, err := databaseClient.GetMatches(ctx, 1234)
+
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []int{5678}, matches.MatchList)
+}
+
+// Match the transaction that applies a user's swipes, it starts with the stats update of their row
+func applyForUser(userId string) interface{} {
+	return mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		update := input.TransactItems[0].Update
+		return update != nil && update.ExpressionAttributeNames != nil &&
+			update.Key["userId"].(*types.AttributeValueMemberN).Value == userId
+	})
+}
+
+// Match the transaction that adds a mutual match
+func addMatch() interface{} {
+	return mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return input.TransactItems[0].Update.ConditionExpression != nil
+	})
+}
+
+// Apply swipe happy path
+func TestApplySwipe(t *testing.T) {
 	tests := []struct {
 		name     string
-		userId   int
-		swipee   int
 		swipeDir string
+		matchErr error // the result of the mutual match transaction
 	}{
 		{
-			name:     "swipe right",
-			userId:   1234,
-			swipee:   5678,
+			name:     "swipe right mutual match",
+			swipeDir: "right",
+			matchErr: nil,
+		},
+		{
+			name:     "swipe right no match yet",
 			swipeDir: "right",
+			matchErr: &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("ConditionalCheckFailed")},
+					{Code: aws.String("None")},
+				},
+			},
 		},
 		{
 			name:     "swipe left",
-			userId:   1234,
-			swipee:   5678,
 			swipeDir: "left",
 		},
 	}
@@ -39,37 +87,42 @@ func TestUpdateUserStats(t *testing.T) {
 			mockDynamoClient := mocks.NewDynamoClienter(t)
 
 			mockDynamoClient.EXPECT().
-				UpdateItem(ctx, mock.Anything, mock.Anything).
-				Return(&dynamodb.UpdateItemOutput{}, nil)
+				TransactWriteItems(ctx, applyForUser("1234"), mock.Anything).
+				Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+			if tc.swipeDir == "right" {
+				mockDynamoClient.EXPECT().
+					TransactWriteItems(ctx, addMatch(), mock.Anything).
+					Return(&dynamodb.TransactWriteItemsOutput{}, tc.matchErr).Once()
+			}
 
-			databaseClient := DatabaseClient{
+			databaseClient := store.DatabaseClient{
 				Client: mockDynamoClient,
 			}
 
-			err := databaseClient.UpdateUserStats(ctx, tc.userId, tc.swipee, tc.swipeDir)
+			err := databaseClient.ApplySwipe(ctx, store.Swipe{SwipeId: xid.New().String(), UserId: 1234, Swipee: 5678, Direction: tc.swipeDir})
 
 			assert.NoError(t, err)
 		})
 	}
 }
 
-// Update user stats sad path
-func TestUpdateUserStatsError(t *testing.T) {
+// Apply swipe sad path
+func TestApplySwipeError(t *testing.T) {
 	tests := []struct {
-		name              string
-		userId            int
-		swipee            int
-		swipeDir          string
-		mockInternalError error
-		expectedErrorMsg  string // the high level error message
+		name             string
+		mockApplyError   error
+		mockMatchError   error
+		expectedErrorMsg string // the high level error message
 	}{
 		{
-			name:              "dynamo internal error",
-			userId:            1234,
-			swipee:            5678,
-			swipeDir:          "right",
-			mockInternalError: errors.New("aws died"),
-			expectedErrorMsg:  "UpdateItem failed: aws died",
+			name:             "dynamo internal error",
+			mockApplyError:   errors.New("aws died"),
+			expectedErrorMsg: "TransactWriteItems failed: aws died",
+		},
+		{
+			name:             "dynamo match error",
+			mockMatchError:   errors.New("aws died"),
+			expectedErrorMsg: "failed to add match: aws died",
 		},
 	}
 	for _, tc := range tests {
@@ -78,17 +131,241 @@ func TestUpdateUserStatsError(t *testing.T) {
 			mockDynamoClient := mocks.NewDynamoClienter(t)
 
 			mockDynamoClient.EXPECT().
-				UpdateItem(ctx, mock.Anything, mock.Anything).
-				Return(&dynamodb.UpdateItemOutput{}, tc.mockInternalError)
+				TransactWriteItems(ctx, applyForUser("1234"), mock.Anything).
+				Return(&dynamodb.TransactWriteItemsOutput{}, tc.mockApplyError).Once()
+			if tc.mockMatchError != nil {
+				mockDynamoClient.EXPECT().
+					TransactWriteItems(ctx, addMatch(), mock.Anything).
+					Return(nil, tc.mockMatchError).Once()
+			}
 
-			databaseClient := DatabaseClient{
+			databaseClient := store.DatabaseClient{
 				Client: mockDynamoClient,
 			}
 
-			err := databaseClient.UpdateUserStats(ctx, tc.userId, tc.swipee, tc.swipeDir)
+			err := databaseClient.ApplySwipe(ctx, store.Swipe{UserId: 1234, Swipee: 5678, Direction: "right"})
 
 			assert.Error(t, err)
 			assert.Equal(t, tc.expectedErrorMsg, err.Error())
 		})
 	}
 }
+
+// The comment and swipe id are written with the stats. A retried swipe writes the same comment again.
+func TestApplySwipeComment(t *testing.T) {
+	ctx := context.Background()
+	mockDynamoClient := mocks.NewDynamoClienter(t)
+
+	var commentIds []string
+	mockDynamoClient.EXPECT().
+		TransactWriteItems(ctx, applyForUser("1234"), mock.Anything).
+		Run(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) {
+			assert.Len(t, input.TransactItems, 3)
+			marker := input.TransactItems[1].Put
+			assert.Equal(t, "AppliedSwipes", *marker.TableName)
+			assert.Equal(t, "attribute_not_exists(swipeId)", *marker.ConditionExpression)
+			comment := input.TransactItems[2].Put
+			assert.Equal(t, "SwipeComments", *comment.TableName)
+			assert.Equal(t, "5678", comment.Item["userId"].(*types.AttributeValueMemberN).Value)
+			commentIds = append(commentIds, comment.Item["commentId"].(*types.AttributeValueMemberS).Value)
+		}).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Twice()
+
+	databaseClient := store.DatabaseClient{
+		Client: mockDynamoClient,
+	}
+
+	swipe := store.Swipe{SwipeId: xid.New().String(), UserId: 1234, Swipee: 5678, Direction: "left", Comment: "hello"}
+	assert.NoError(t, databaseClient.ApplySwipe(ctx, swipe))
+	assert.NoError(t, databaseClient.ApplySwipe(ctx, swipe))
+
+	assert.Len(t, commentIds, 2)
+	assert.Equal(t, commentIds[0], commentIds[1])
+	assert.True(t, strings.HasSuffix(commentIds[0], "#"+swipe.SwipeId))
+}
+
+// A redelivered swipe doesn't count twice, but its match is still checked in case that failed the first time
+func TestApplySwipeAlreadyApplied(t *testing.T) {
+	ctx := context.Background()
+	mockDynamoClient := mocks.NewDynamoClienter(t)
+
+	mockDynamoClient.EXPECT().
+		TransactWriteItems(ctx, applyForUser("1234"), mock.Anything).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			},
+		}).Once()
+	mockDynamoClient.EXPECT().
+		TransactWriteItems(ctx, addMatch(), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	databaseClient := store.DatabaseClient{
+		Client: mockDynamoClient,
+	}
+
+	err := databaseClient.ApplySwipe(ctx, store.Swipe{SwipeId: xid.New().String(), UserId: 1234, Swipee: 5678, Direction: "right"})
+
+	assert.NoError(t, err)
+}
+
+// Swipes are merged into one transaction per user
+func TestUpdateUserStatsBatch(t *testing.T) {
+	ctx := context.Background()
+	mockDynamoClient := mocks.NewDynamoClienter(t)
+
+	mockDynamoClient.EXPECT().
+		TransactWriteItems(ctx, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+			update := input.TransactItems[0].Update
+			likedList, ok := update.ExpressionAttributeValues[":2"].(*types.AttributeValueMemberNS)
+			return update.Key["userId"].(*types.AttributeValueMemberN).Value == "1234" &&
+				ok && assert.ElementsMatch(t, []string{"5678", "5679"}, likedList.Value) &&
+				len(input.TransactItems) == 2 && *input.TransactItems[1].Put.TableName == "SwipeComments"
+		}), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, applyForUser("5678"), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+	// 1234 and 5678 liked each other in the same batch, so only one match check for the pair
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, addMatch(), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Twice()
+
+	databaseClient := store.DatabaseClient{
+		Client: mockDynamoClient,
+	}
+
+	err := databaseClient.UpdateUserStatsBatch(ctx, []store.Swipe{
+		{UserId: 1234, Swipee: 5678, Direction: "right"},
+		{UserId: 1234, Swipee: 5679, Direction: "right"},
+		{UserId: 1234, Swipee: 5680, Direction: "left", Comment: "no thanks"},
+		{UserId: 5678, Swipee: 1234, Direction: "right"},
+	})
+
+	assert.NoError(t, err)
+}
+
+// Swipes that were applied before are dropped and the rest of the user's swipes are written
+func TestUpdateUserStatsBatchSkipsApplied(t *testing.T) {
+	ctx := context.Background()
+	mockDynamoClient := mocks.NewDynamoClienter(t)
+
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return len(input.TransactItems) == 3
+	}), mock.Anything).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+				{Code: aws.String("None")},
+			},
+		}).Once()
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return len(input.TransactItems) == 2 &&
+			input.TransactItems[1].Put.Item["swipeId"].(*types.AttributeValueMemberS).Value == "b" &&
+			input.TransactItems[0].Update.ExpressionAttributeValues[":0"].(*types.AttributeValueMemberN).Value == "1" // One dislike
+	}), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	databaseClient := store.DatabaseClient{
+		Client: mockDynamoClient,
+	}
+
+	err := databaseClient.UpdateUserStatsBatch(ctx, []store.Swipe{
+		{SwipeId: "a", UserId: 1234, Swipee: 9999, Direction: "left"},
+		{SwipeId: "b", UserId: 1234, Swipee: 9998, Direction: "left"},
+		{SwipeId: "b", UserId: 1234, Swipee: 9998, Direction: "left"}, // Redelivered into the same batch
+	})
+
+	assert.NoError(t, err)
+}
+
+// A user with more swipes than fit in one transaction is split into several
+func TestUpdateUserStatsBatchSplitsTransactions(t *testing.T) {
+	ctx := context.Background()
+	mockDynamoClient := mocks.NewDynamoClienter(t)
+
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return len(input.TransactItems) <= 100
+	}), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Twice()
+
+	databaseClient := store.DatabaseClient{
+		Client: mockDynamoClient,
+	}
+
+	swipes := make([]store.Swipe, 60) // 121 items with the stats update
+	for i := range swipes {
+		swipes[i] = store.Swipe{SwipeId: xid.New().String(), UserId: 1234, Swipee: i, Direction: "left", Comment: "no"}
+	}
+	err := databaseClient.UpdateUserStatsBatch(ctx, swipes)
+
+	assert.NoError(t, err)
+}
+
+// Only the swipes of the user whose write failed are reported
+func TestUpdateUserStatsBatchPartialFailure(t *testing.T) {
+	ctx := context.Background()
+	mockDynamoClient := mocks.NewDynamoClienter(t)
+
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, applyForUser("1234"), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, applyForUser("5678"), mock.Anything).
+		Return(nil, errors.New("aws died")).Once()
+
+	databaseClient := store.DatabaseClient{
+		Client: mockDynamoClient,
+	}
+
+	err := databaseClient.UpdateUserStatsBatch(ctx, []store.Swipe{
+		{UserId: 1234, Swipee: 9999, Direction: "left"},
+		{UserId: 5678, Swipee: 9999, Direction: "left"},
+		{UserId: 5678, Swipee: 9998, Direction: "left"},
+	})
+
+	var batchErr *store.BatchError
+	assert.ErrorAs(t, err, &batchErr)
+	assert.Len(t, batchErr.Errs, 2)
+	assert.NotContains(t, batchErr.Errs, 0)
+	assert.EqualError(t, batchErr.Errs[1], "TransactWriteItems failed: aws died")
+	assert.EqualError(t, batchErr.Errs[2], "TransactWriteItems failed: aws died")
+}
+
+// While the swipee is being moved to another shard, their like may still be in the old table
+func TestUpdateUserStatsMatchWhileMigrating(t *testing.T) {
+	ctx := context.Background()
+	mockDynamoClient := mocks.NewDynamoClienter(t)
+	router, err := shard.NewRouter(shard.Config{
+		Ranges:   []shard.Range{{Max: 2000, Table: "SwipeData1"}, {Table: "SwipeData2"}},
+		Previous: &shard.Config{Ranges: []shard.Range{{Table: "SwipeData1"}}},
+	})
+	assert.NoError(t, err)
+
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, applyForUser("1234"), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, addMatch(), mock.Anything).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}},
+		}).Once()
+	mockDynamoClient.EXPECT().GetItem(ctx, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return *input.TableName == "SwipeData1"
+	}), mock.Anything).Return(&dynamodb.GetItemOutput{
+		Item: map[string]types.AttributeValue{
+			"likedList": &types.AttributeValueMemberNS{Value: []string{"1234"}},
+		},
+	}, nil).Once()
+	mockDynamoClient.EXPECT().TransactWriteItems(ctx, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		update := input.TransactItems[0].Update
+		return update.ConditionExpression == nil && update.ExpressionAttributeNames == nil &&
+			*update.TableName == "SwipeData2"
+	}), mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	databaseClient := store.DatabaseClient{
+		Client: mockDynamoClient,
+		Router: router,
+	}
+
+	err = databaseClient.ApplySwipe(ctx, store.Swipe{UserId: 1234, Swipee: 3000, Direction: "right"})
+
+	assert.NoError(t, err)
+}
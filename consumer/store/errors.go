@@ -0,0 +1,58 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// How a failed write should be handled
+type ErrorKind int
+
+const (
+	Transient ErrorKind = iota // Retry later
+	Throttled                  // Retry later and slow down
+	Permanent                  // Retrying won't help
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case Throttled:
+		return "throttled"
+	case Permanent:
+		return "permanent"
+	default:
+		return "transient"
+	}
+}
+
+// Classify a store error. Unknown errors are assumed to be transient since retries are bounded anyway.
+func ClassifyError(err error) ErrorKind {
+	// https://github.com/golang/go/issues/37625#issuecomment-594033043
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			switch aws.ToString(reason.Code) {
+			case "ThrottlingError", "ProvisionedThroughputExceeded":
+				return Throttled
+			case "ValidationError", "ItemCollectionSizeLimitExceeded":
+				return Permanent
+			}
+		}
+		return Transient
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ProvisionedThroughputExceededException", "RequestLimitExceeded", "ThrottlingException":
+			return Throttled
+		case "ValidationException", "ResourceNotFoundException", "ConditionalCheckFailedException",
+			"ItemCollectionSizeLimitExceededException", "AccessDeniedException", "UnrecognizedClientException":
+			return Permanent
+		}
+		return Transient
+	}
+	return Transient
+}
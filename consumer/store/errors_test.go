@@ -0,0 +1,63 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{
+			name: "provisioned throughput exceeded",
+			err:  fmt.Errorf("UpdateItem failed: %w", &types.ProvisionedThroughputExceededException{}),
+			want: Throttled,
+		},
+		{
+			name: "request limit exceeded",
+			err:  &smithy.GenericAPIError{Code: "RequestLimitExceeded"},
+			want: Throttled,
+		},
+		{
+			name: "throttled transaction",
+			err: &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{{Code: aws.String("None")}, {Code: aws.String("ThrottlingError")}},
+			},
+			want: Throttled,
+		},
+		{
+			name: "validation",
+			err:  fmt.Errorf("UpdateItem failed: %w", &smithy.GenericAPIError{Code: "ValidationException"}),
+			want: Permanent,
+		},
+		{
+			name: "table not found",
+			err:  &types.ResourceNotFoundException{},
+			want: Permanent,
+		},
+		{
+			name: "internal server error",
+			err:  &types.InternalServerError{},
+			want: Transient,
+		},
+		{
+			name: "unknown",
+			err:  errors.New("connection reset"),
+			want: Transient,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ClassifyError(tc.err))
+		})
+	}
+}
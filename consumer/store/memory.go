@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+)
+
+// Database client that keeps everything in a hashmap. Optionally snapshots to a file on disk.
+// Only the consumer can see the hashmap, so it's meant for load testing the consumer on its own.
+type MemoryClient struct {
+	mu       sync.RWMutex
+	users    map[int]*memoryUser
+	comments map[int][]models.Comment // Keyed by the user who received the comment
+	applied  map[string]time.Time     // swipe id -> when it's forgotten
+	swept    time.Time                // when expired swipe ids were last forgotten
+	path     string                   // snapshot file, empty means no snapshots
+	ticker   *time.Ticker
+	quit     chan struct{}
+	done     chan struct{}
+
+	AppliedTTL time.Duration // How long applied swipe ids are remembered, zero means a day
+}
+
+// A single user row
+type memoryUser struct {
+	numLikes    int
+	numDislikes int
+	likes       map[int]struct{}
+	matches     map[int]struct{}
+}
+
+// The snapshot file format
+type memorySnapshot struct {
+	Users    []models.DynamoUserStats `json:"users"`
+	Comments map[int][]models.Comment `json:"comments"`
+	Applied  map[string]time.Time     `json:"applied"`
+}
+
+// Create a new MemoryClient. If path is set, the previous snapshot is restored and a new snapshot is
+// written every interval.
+func NewMemoryClient(path string, interval time.Duration) (*MemoryClient, error) {
+	m := &MemoryClient{
+		users:    make(map[int]*memoryUser),
+		comments: make(map[int][]models.Comment),
+		applied:  make(map[string]time.Time),
+		swept:    time.Now(),
+		path:     path,
+	}
+	if path == "" {
+		return m, nil
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid snapshot interval: %v", interval)
+	}
+	if err := m.Restore(); err != nil {
+		return nil, err
+	}
+	m.ticker = time.NewTicker(interval)
+	m.quit = make(chan struct{})
+	m.done = make(chan struct{})
+	go func() { // Snapshot goroutine
+		defer close(m.done)
+		for {
+			select {
+			case <-m.quit:
+				return
+			case <-m.ticker.C:
+				if err := m.Snapshot(); err != nil {
+					zlog.Error().Err(err).Msg("unable to snapshot memory store")
+				}
+			}
+		}
+	}()
+	return m, nil
+}
+
+// Get user likes and dislikes. Returns found, user stats, and error.
+func (m *MemoryClient) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[userId]
+	if !ok {
+		return false, models.UserStats{}, nil // not found
+	}
+	return true,
+		models.UserStats{
+			NumLikes:    user.numLikes,
+			NumDislikes: user.numDislikes,
+		},
+		nil
+}
+
+// Get user matches list. Returns found, user matches, and error.
+func (m *MemoryClient) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[userId]
+	if !ok {
+		return false, models.UserMatches{}, nil // not found
+	}
+	return true,
+		models.UserMatches{
+			MatchList: sortedKeys(user.matches),
+		},
+		nil
+}
+
+// Apply a swipe and its comment under one lock. A swipe whose id was already applied is skipped.
+func (m *MemoryClient) ApplySwipe(ctx context.Context, swipe Swipe) error {
+	if swipe.Direction != "right" && swipe.Direction != "left" {
+		return fmt.Errorf("invalid swipe direction: %s", swipe.Direction)
+	}
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if swipe.SwipeId != "" {
+		if expiry, ok := m.applied[swipe.SwipeId]; ok && now.Before(expiry) {
+			return nil
+		}
+		m.forgetApplied(now)
+		m.applied[swipe.SwipeId] = now.Add(appliedTTL(m.AppliedTTL))
+	}
+	user := m.getOrCreate(swipe.UserId)
+	if swipe.Direction == "right" {
+		user.numLikes++
+		user.likes[swipe.Swipee] = struct{}{}
+		// Match only if the swipee already liked this user. Liking yourself is not a match.
+		if other, ok := m.users[swipe.Swipee]; ok && swipe.Swipee != swipe.UserId {
+			if _, liked := other.likes[swipe.UserId]; liked {
+				user.matches[swipe.Swipee] = struct{}{}
+				other.matches[swipe.UserId] = struct{}{}
+			}
+		}
+	} else {
+		user.numDislikes++
+	}
+	if swipe.Comment != "" {
+		m.comments[swipe.Swipee] = append(m.comments[swipe.Swipee], models.Comment{
+			From:      swipe.UserId,
+			Comment:   swipe.Comment,
+			Direction: swipe.Direction,
+			CreatedAt: now.UTC().Format(time.RFC3339),
+		})
+	}
+	return nil
+}
+
+// Internal method that forgets expired swipe ids, at most once per ttl. Caller must hold the write lock.
+func (m *MemoryClient) forgetApplied(now time.Time) {
+	if now.Sub(m.swept) < appliedTTL(m.AppliedTTL) {
+		return
+	}
+	for swipeId, expiry := range m.applied {
+		if !now.Before(expiry) {
+			delete(m.applied, swipeId)
+		}
+	}
+	m.swept = now
+}
+
+// Write every user, comment, and applied swipe id to the snapshot file. The file is replaced atomically.
+func (m *MemoryClient) Snapshot() error {
+	m.mu.RLock()
+	snapshot := memorySnapshot{
+		Users:    make([]models.DynamoUserStats, 0, len(m.users)),
+		Comments: m.comments,
+		Applied:  m.applied,
+	}
+	for userId, user := range m.users {
+		snapshot.Users = append(snapshot.Users, models.DynamoUserStats{
+			UserId:      userId,
+			NumLikes:    user.numLikes,
+			NumDislikes: user.numDislikes,
+			LikedList:   sortedKeys(user.likes),
+			MatchList:   sortedKeys(user.matches),
+		})
+	}
+	data, err := json.Marshal(snapshot) // Marshal under the lock since comments are shared
+	m.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load the snapshot file into memory. A missing file means a fresh start.
+func (m *MemoryClient) Restore() error {
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var snapshot memorySnapshot
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, item := range snapshot.Users {
+		user := m.getOrCreate(item.UserId)
+		user.numLikes = item.NumLikes
+		user.numDislikes = item.NumDislikes
+		for _, likedId := range item.LikedList {
+			user.likes[likedId] = struct{}{}
+		}
+		for _, matchId := range item.MatchList {
+			user.matches[matchId] = struct{}{}
+		}
+	}
+	for userId, comments := range snapshot.Comments {
+		m.comments[userId] = append(m.comments[userId], comments...)
+	}
+	for swipeId, expiry := range snapshot.Applied {
+		m.applied[swipeId] = expiry
+	}
+	zlog.Info().Int("users", len(snapshot.Users)).Str("path", m.path).Msg("restored memory store snapshot")
+	return nil
+}
+
+// Stop the snapshot goroutine and write one final snapshot
+func (m *MemoryClient) Close() error {
+	if m.path == "" {
+		return nil
+	}
+	m.ticker.Stop()
+	close(m.quit)
+	<-m.done
+	return m.Snapshot()
+}
+
+// Internal method that gets a user row, creating it if needed. Caller must hold the write lock.
+func (m *MemoryClient) getOrCreate(userId int) *memoryUser {
+	user, ok := m.users[userId]
+	if !ok {
+		user = &memoryUser{
+			likes:   make(map[int]struct{}),
+			matches: make(map[int]struct{}),
+		}
+		m.users[userId] = user
+	}
+	return user
+}
+
+// Return the keys of a set in ascending order
+func sortedKeys(set map[int]struct{}) []int {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]int, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Update then read back user stats and matches
+func TestMemoryUpdateAndGet(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewMemoryClient("", 0)
+	require.NoError(t, err)
+
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5679, Direction: "right"}))
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5678, Direction: "right"}))
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5680, Direction: "left"}))
+	assert.EqualError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5680, Direction: "middle"}), "invalid swipe direction: middle")
+
+	found, stats, err := client.GetUserStats(ctx, 1234)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 2, stats.NumLikes)
+	assert.Equal(t, 1, stats.NumDislikes)
+
+	// 5679 likes 1234 back, so only they are a match
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 5679, Swipee: 1234, Direction: "right"}))
+
+	found, matches, err := client.GetMatches(ctx, 1234)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []int{5679}, matches.MatchList)
+
+	_, matches, err = client.GetMatches(ctx, 5679)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1234}, matches.MatchList)
+
+	found, _, err = client.GetUserStats(ctx, 4321)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+// Data survives a restart through the snapshot file
+func TestMemorySnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	client, err := NewMemoryClient(path, time.Hour)
+	require.NoError(t, err)
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5678, Direction: "right"}))
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5679, Direction: "left"}))
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{SwipeId: "abc", UserId: 5678, Swipee: 1234, Direction: "right", Comment: "hello"}))
+	require.NoError(t, client.Close()) // Writes the final snapshot
+
+	restored, err := NewMemoryClient(path, time.Hour)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	found, stats, err := restored.GetUserStats(ctx, 1234)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, stats.NumLikes)
+	assert.Equal(t, 1, stats.NumDislikes)
+
+	_, matches, err := restored.GetMatches(ctx, 1234)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5678}, matches.MatchList)
+
+	assert.Len(t, restored.comments[1234], 1)
+	assert.Equal(t, "hello", restored.comments[1234][0].Comment)
+
+	// Applied swipe ids survive too, so a redelivery after the restart is skipped
+	assert.NoError(t, restored.ApplySwipe(ctx, Swipe{SwipeId: "abc", UserId: 5678, Swipee: 1234, Direction: "right", Comment: "hello"}))
+	_, stats, err = restored.GetUserStats(ctx, 5678)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.NumLikes)
+	assert.Len(t, restored.comments[1234], 1)
+}
@@ -0,0 +1,203 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	mock "github.com/stretchr/testify/mock"
+
+	store "github.com/DennisPing/cs6650-twinder-a3/consumer/store"
+)
+
+// Store is an autogenerated mock type for the Store type
+type Store struct {
+	mock.Mock
+}
+
+type Store_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Store) EXPECT() *Store_Expecter {
+	return &Store_Expecter{mock: &_m.Mock}
+}
+
+// ApplySwipe provides a mock function with given fields: ctx, swipe
+func (_m *Store) ApplySwipe(ctx context.Context, swipe store.Swipe) error {
+	ret := _m.Called(ctx, swipe)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, store.Swipe) error); ok {
+		r0 = rf(ctx, swipe)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Store_ApplySwipe_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApplySwipe'
+type Store_ApplySwipe_Call struct {
+	*mock.Call
+}
+
+// ApplySwipe is a helper method to define mock.On call
+//   - ctx context.Context
+//   - swipe store.Swipe
+func (_e *Store_Expecter) ApplySwipe(ctx interface{}, swipe interface{}) *Store_ApplySwipe_Call {
+	return &Store_ApplySwipe_Call{Call: _e.mock.On("ApplySwipe", ctx, swipe)}
+}
+
+func (_c *Store_ApplySwipe_Call) Run(run func(ctx context.Context, swipe store.Swipe)) *Store_ApplySwipe_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(store.Swipe))
+	})
+	return _c
+}
+
+func (_c *Store_ApplySwipe_Call) Return(_a0 error) *Store_ApplySwipe_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Store_ApplySwipe_Call) RunAndReturn(run func(context.Context, store.Swipe) error) *Store_ApplySwipe_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetMatches provides a mock function with given fields: ctx, userId
+func (_m *Store) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 bool
+	var r1 models.UserMatches
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (bool, models.UserMatches, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) bool); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) models.UserMatches); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Get(1).(models.UserMatches)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int) error); ok {
+		r2 = rf(ctx, userId)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Store_GetMatches_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMatches'
+type Store_GetMatches_Call struct {
+	*mock.Call
+}
+
+// GetMatches is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *Store_Expecter) GetMatches(ctx interface{}, userId interface{}) *Store_GetMatches_Call {
+	return &Store_GetMatches_Call{Call: _e.mock.On("GetMatches", ctx, userId)}
+}
+
+func (_c *Store_GetMatches_Call) Run(run func(ctx context.Context, userId int)) *Store_GetMatches_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Store_GetMatches_Call) Return(_a0 bool, _a1 models.UserMatches, _a2 error) *Store_GetMatches_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Store_GetMatches_Call) RunAndReturn(run func(context.Context, int) (bool, models.UserMatches, error)) *Store_GetMatches_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUserStats provides a mock function with given fields: ctx, userId
+func (_m *Store) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 bool
+	var r1 models.UserStats
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (bool, models.UserStats, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) bool); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) models.UserStats); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Get(1).(models.UserStats)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int) error); ok {
+		r2 = rf(ctx, userId)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Store_GetUserStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserStats'
+type Store_GetUserStats_Call struct {
+	*mock.Call
+}
+
+// GetUserStats is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *Store_Expecter) GetUserStats(ctx interface{}, userId interface{}) *Store_GetUserStats_Call {
+	return &Store_GetUserStats_Call{Call: _e.mock.On("GetUserStats", ctx, userId)}
+}
+
+func (_c *Store_GetUserStats_Call) Run(run func(ctx context.Context, userId int)) *Store_GetUserStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Store_GetUserStats_Call) Return(_a0 bool, _a1 models.UserStats, _a2 error) *Store_GetUserStats_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Store_GetUserStats_Call) RunAndReturn(run func(context.Context, int) (bool, models.UserStats, error)) *Store_GetUserStats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+type mockConstructorTestingTNewStore interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewStore creates a new instance of Store. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewStore(t mockConstructorTestingTNewStore) *Store {
+	mock := &Store{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
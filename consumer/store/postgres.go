@@ -0,0 +1,272 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/schema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Record many swipe ids at once. Returns the ids that weren't applied before.
+// A transaction inserting the same id waits for this one to commit, then skips it.
+const claimSwipesSql = `
+INSERT INTO applied_swipes (swipe_id)
+SELECT unnest($1::text[])
+ON CONFLICT DO NOTHING
+RETURNING swipe_id`
+
+// Forget the swipe ids that are older than the ttl
+const forgetSwipesSql = `
+DELETE FROM applied_swipes WHERE applied_at < now() - make_interval(secs => $1)`
+
+// Upsert many users at once. Each userId must appear only once per statement.
+const upsertStatsSql = `
+INSERT INTO user_stats (user_id, num_likes, num_dislikes)
+SELECT * FROM unnest($1::integer[], $2::integer[], $3::integer[])
+ON CONFLICT (user_id) DO UPDATE SET
+	num_likes = user_stats.num_likes + excluded.num_likes,
+	num_dislikes = user_stats.num_dislikes + excluded.num_dislikes`
+
+// Serialize transactions that touch the same pair of users so concurrent mutual likes can't miss each other.
+// Locks are taken in a fixed order to avoid deadlocks.
+const lockPairsSql = `
+SELECT pg_advisory_xact_lock(a, b) FROM (
+	SELECT DISTINCT LEAST(u, v) AS a, GREATEST(u, v) AS b
+	FROM unnest($1::integer[], $2::integer[]) AS t(u, v)
+	ORDER BY a, b
+) AS pairs`
+
+// Insert many likes at once. Duplicates are ignored.
+const insertLikesSql = `
+INSERT INTO likes (user_id, liked_id)
+SELECT * FROM unnest($1::integer[], $2::integer[])
+ON CONFLICT DO NOTHING`
+
+// Add a match in both directions for every pair that has liked each other
+const insertMatchesSql = `
+INSERT INTO matches (user_id, match_id)
+SELECT pair.u, pair.v FROM (
+	SELECT u, v FROM unnest($1::integer[], $2::integer[]) AS t(u, v)
+	UNION
+	SELECT v, u FROM unnest($1::integer[], $2::integer[]) AS t(u, v)
+) AS pair
+WHERE pair.u <> pair.v
+AND EXISTS (SELECT 1 FROM likes WHERE user_id = pair.u AND liked_id = pair.v)
+AND EXISTS (SELECT 1 FROM likes WHERE user_id = pair.v AND liked_id = pair.u)
+ON CONFLICT DO NOTHING`
+
+// Insert many comments at once
+const insertCommentsSql = `
+INSERT INTO comments (user_id, from_id, comment, direction)
+SELECT * FROM unnest($1::integer[], $2::integer[], $3::text[], $4::text[])`
+
+// Database client that stores everything in PostgreSQL
+type PostgresClient struct {
+	Pool *pgxpool.Pool
+
+	AppliedTTL time.Duration // How long applied swipe ids are remembered, zero means a day
+}
+
+// Create a new PostgresClient. The tables are created if they don't exist.
+func NewPostgresClient(url string) (*PostgresClient, error) {
+	if url == "" {
+		return nil, errors.New("you forgot to set the POSTGRES_URL env variable")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = pool.Exec(ctx, schema.Postgres); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return &PostgresClient{
+		Pool: pool,
+	}, nil
+}
+
+// Get user likes and dislikes. Returns found, user stats, and error.
+func (p *PostgresClient) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	var userStats models.UserStats
+	err := p.Pool.QueryRow(ctx,
+		`SELECT num_likes, num_dislikes FROM user_stats WHERE user_id = $1`, userId,
+	).Scan(&userStats.NumLikes, &userStats.NumDislikes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, userStats, nil // not found
+	}
+	if err != nil {
+		return false, userStats, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	return true, userStats, nil
+}
+
+// Get user matches list. Returns found, user matches, and error.
+func (p *PostgresClient) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	var userMatches models.UserMatches
+	found, _, err := p.GetUserStats(ctx, userId)
+	if err != nil || !found {
+		return false, userMatches, err
+	}
+
+	rows, err := p.Pool.Query(ctx,
+		`SELECT match_id FROM matches WHERE user_id = $1 ORDER BY match_id`, userId)
+	if err != nil {
+		return false, userMatches, fmt.Errorf("failed to get matches: %w", err)
+	}
+	matchList, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return false, userMatches, fmt.Errorf("failed to get matches: %w", err)
+	}
+	userMatches.MatchList = matchList
+	return true, userMatches, nil
+}
+
+// Apply a swipe. The stats, like, match, comment, and swipe id are written in one transaction.
+func (p *PostgresClient) ApplySwipe(ctx context.Context, swipe Swipe) error {
+	return p.UpdateUserStatsBatch(ctx, []Swipe{swipe})
+}
+
+// Apply many swipes and their comments in a single transaction. Either all swipes are applied or none are.
+// Swipes whose id was applied by an earlier transaction are skipped.
+func (p *PostgresClient) UpdateUserStatsBatch(ctx context.Context, swipes []Swipe) error {
+	if _, err := aggregateSwipes(swipes); err != nil { // Reject invalid swipes before touching the database
+		return err
+	}
+
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	swipes, err = p.claimSwipes(ctx, tx, swipes)
+	if err != nil {
+		return err
+	}
+	if len(swipes) == 0 {
+		return nil // Every swipe was already applied
+	}
+	delta, err := aggregateSwipes(swipes)
+	if err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	if len(delta.likeUserIds) > 0 {
+		batch.Queue(lockPairsSql, delta.likeUserIds, delta.likedIds)
+		batch.Queue(insertLikesSql, delta.likeUserIds, delta.likedIds)
+		batch.Queue(insertMatchesSql, delta.likeUserIds, delta.likedIds)
+	}
+	batch.Queue(upsertStatsSql, delta.userIds, delta.numLikes, delta.numDislikes)
+	if len(delta.commentUserIds) > 0 {
+		batch.Queue(insertCommentsSql, delta.commentUserIds, delta.commentFromIds, delta.comments, delta.commentDirections)
+	}
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to update batch: %w", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+	return nil
+}
+
+// Internal method that records the swipe ids in tx and drops the swipes that were already applied.
+// Swipes without an id are always kept. A redelivery in the same batch is only kept once.
+func (p *PostgresClient) claimSwipes(ctx context.Context, tx pgx.Tx, swipes []Swipe) ([]Swipe, error) {
+	var swipeIds []string
+	for _, swipe := range swipes {
+		if swipe.SwipeId != "" {
+			swipeIds = append(swipeIds, swipe.SwipeId)
+		}
+	}
+	if len(swipeIds) == 0 {
+		return swipes, nil
+	}
+	if _, err := tx.Exec(ctx, forgetSwipesSql, appliedTTL(p.AppliedTTL).Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to forget swipe ids: %w", err)
+	}
+	rows, err := tx.Query(ctx, claimSwipesSql, swipeIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add swipe ids: %w", err)
+	}
+	claimedIds, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to add swipe ids: %w", err)
+	}
+	claimed := make(map[string]bool, len(claimedIds))
+	for _, swipeId := range claimedIds {
+		claimed[swipeId] = true
+	}
+	kept := make([]Swipe, 0, len(swipes))
+	for _, swipe := range swipes {
+		if swipe.SwipeId == "" || claimed[swipe.SwipeId] {
+			kept = append(kept, swipe)
+			delete(claimed, swipe.SwipeId)
+		}
+	}
+	return kept, nil
+}
+
+// Close the connection pool
+func (p *PostgresClient) Close() error {
+	p.Pool.Close()
+	return nil
+}
+
+// Column arrays for the unnest upserts
+type swipeDelta struct {
+	userIds     []int
+	numLikes    []int
+	numDislikes []int
+	likeUserIds []int
+	likedIds    []int
+
+	commentUserIds    []int // The swipee receives the comment
+	commentFromIds    []int
+	comments          []string
+	commentDirections []string
+}
+
+// Merge swipes into one row per user since Postgres can't upsert the same row twice in one statement.
+// Rows are sorted by userId so concurrent batches lock the user_stats rows in the same order and can't deadlock.
+func aggregateSwipes(swipes []Swipe) (*swipeDelta, error) {
+	delta := &swipeDelta{}
+	counts := make(map[int]*[2]int) // userId -> likes, dislikes
+	for _, swipe := range swipes {
+		count, ok := counts[swipe.UserId]
+		if !ok {
+			count = &[2]int{}
+			counts[swipe.UserId] = count
+			delta.userIds = append(delta.userIds, swipe.UserId)
+		}
+		switch swipe.Direction {
+		case "right":
+			count[0]++
+			delta.likeUserIds = append(delta.likeUserIds, swipe.UserId)
+			delta.likedIds = append(delta.likedIds, swipe.Swipee)
+		case "left":
+			count[1]++
+		default:
+			return nil, fmt.Errorf("invalid swipe direction: %s", swipe.Direction)
+		}
+		if swipe.Comment != "" {
+			delta.commentUserIds = append(delta.commentUserIds, swipe.Swipee)
+			delta.commentFromIds = append(delta.commentFromIds, swipe.UserId)
+			delta.comments = append(delta.comments, swipe.Comment)
+			delta.commentDirections = append(delta.commentDirections, swipe.Direction)
+		}
+	}
+	sort.Ints(delta.userIds)
+	for _, userId := range delta.userIds {
+		delta.numLikes = append(delta.numLikes, counts[userId][0])
+		delta.numDislikes = append(delta.numDislikes, counts[userId][1])
+	}
+	return delta, nil
+}
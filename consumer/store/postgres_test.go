@@ -0,0 +1,35 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Swipes are merged into one row per user before the upsert, sorted by userId
+func TestAggregateSwipes(t *testing.T) {
+	delta, err := aggregateSwipes([]Swipe{
+		{UserId: 4321, Swipee: 5678, Direction: "left"},
+		{UserId: 1234, Swipee: 5678, Direction: "right", Comment: "hello"},
+		{UserId: 1234, Swipee: 5679, Direction: "left"},
+		{UserId: 1234, Swipee: 5680, Direction: "right"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1234, 4321}, delta.userIds)
+	assert.Equal(t, []int{2, 0}, delta.numLikes)
+	assert.Equal(t, []int{1, 1}, delta.numDislikes)
+	assert.Equal(t, []int{1234, 1234}, delta.likeUserIds)
+	assert.Equal(t, []int{5678, 5680}, delta.likedIds)
+	assert.Equal(t, []int{5678}, delta.commentUserIds)
+	assert.Equal(t, []int{1234}, delta.commentFromIds)
+	assert.Equal(t, []string{"hello"}, delta.comments)
+}
+
+func TestAggregateSwipesError(t *testing.T) {
+	_, err := aggregateSwipes([]Swipe{
+		{UserId: 1234, Swipee: 5678, Direction: "middle"},
+	})
+
+	assert.EqualError(t, err, "invalid swipe direction: middle")
+}
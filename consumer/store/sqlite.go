@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Database client that stores everything in a local SQLite file
+type SqliteClient struct {
+	DB *sql.DB
+
+	AppliedTTL time.Duration // How long applied swipe ids are remembered, zero means a day
+}
+
+// Create a new SqliteClient. The database file and tables are created if they don't exist.
+func NewSqliteClient(path string) (*SqliteClient, error) {
+	if path == "" {
+		path = "twinder.db"
+	}
+	// WAL mode lets the httpserver read while the consumer writes to the same file
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.Exec(schema.Sqlite); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return &SqliteClient{
+		DB: db,
+	}, nil
+}
+
+// Get user likes and dislikes. Returns found, user stats, and error.
+func (s *SqliteClient) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	var userStats models.UserStats
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT num_likes, num_dislikes FROM user_stats WHERE user_id = ?`, userId,
+	).Scan(&userStats.NumLikes, &userStats.NumDislikes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, userStats, nil // not found
+	}
+	if err != nil {
+		return false, userStats, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	return true, userStats, nil
+}
+
+// Get user matches list. Returns found, user matches, and error.
+func (s *SqliteClient) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	var userMatches models.UserMatches
+	found, _, err := s.GetUserStats(ctx, userId)
+	if err != nil || !found {
+		return false, userMatches, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT match_id FROM matches WHERE user_id = ? ORDER BY match_id`, userId)
+	if err != nil {
+		return false, userMatches, fmt.Errorf("failed to get matches: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var matchId int
+		if err = rows.Scan(&matchId); err != nil {
+			return false, userMatches, fmt.Errorf("failed to scan match: %w", err)
+		}
+		userMatches.MatchList = append(userMatches.MatchList, matchId)
+	}
+	if err = rows.Err(); err != nil {
+		return false, userMatches, fmt.Errorf("failed to get matches: %w", err)
+	}
+	return true, userMatches, nil
+}
+
+// Apply a swipe. The stats, like, match, comment, and swipe id are written in one transaction,
+// so a swipe whose id is already there was fully applied and is skipped.
+func (s *SqliteClient) ApplySwipe(ctx context.Context, swipe Swipe) error {
+	var likes, dislikes int
+	switch swipe.Direction {
+	case "right":
+		likes = 1
+	case "left":
+		dislikes = 1
+	default:
+		return fmt.Errorf("invalid swipe direction: %s", swipe.Direction)
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if swipe.SwipeId != "" {
+		claimed, err := claimSwipe(ctx, tx, swipe.SwipeId, now, appliedTTL(s.AppliedTTL))
+		if err != nil || !claimed {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_stats (user_id, num_likes, num_dislikes) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			num_likes = num_likes + excluded.num_likes,
+			num_dislikes = num_dislikes + excluded.num_dislikes`,
+		swipe.UserId, likes, dislikes)
+	if err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+	if swipe.Direction == "right" {
+		if err = addMatchIfMutual(ctx, tx, swipe.UserId, swipe.Swipee); err != nil {
+			return err
+		}
+	}
+	if swipe.Comment != "" {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO comments (user_id, from_id, comment, direction, created_at) VALUES (?, ?, ?, ?, ?)`,
+			swipe.Swipee, swipe.UserId, swipe.Comment, swipe.Direction, now.Format(time.RFC3339))
+		if err != nil {
+			return fmt.Errorf("failed to add comment: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Record a swipe id and forget the ids older than ttl. Returns false if the id was already applied.
+func claimSwipe(ctx context.Context, tx *sql.Tx, swipeId string, now time.Time, ttl time.Duration) (bool, error) {
+	result, err := tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO applied_swipes (swipe_id, applied_at) VALUES (?, ?)`, swipeId, now.Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to add swipe id: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to add swipe id: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM applied_swipes WHERE applied_at < ?`, now.Add(-ttl).Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to forget swipe ids: %w", err)
+	}
+	return claimed == 1, nil
+}
+
+// Record the like and add a match for both users if the swipee already liked the user.
+// Write transactions are serialized by SQLite so concurrent mutual swipes can't miss each other.
+func addMatchIfMutual(ctx context.Context, tx *sql.Tx, userId, swipee int) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO likes (user_id, liked_id) VALUES (?, ?)`, userId, swipee)
+	if err != nil {
+		return fmt.Errorf("failed to add like: %w", err)
+	}
+	if userId == swipee {
+		return nil // Liking yourself is not a match
+	}
+	var mutual bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM likes WHERE user_id = ? AND liked_id = ?)`, swipee, userId,
+	).Scan(&mutual)
+	if err != nil {
+		return fmt.Errorf("failed to check like: %w", err)
+	}
+	if !mutual {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO matches (user_id, match_id) VALUES (?, ?), (?, ?)`,
+		userId, swipee, swipee, userId)
+	if err != nil {
+		return fmt.Errorf("failed to add match: %w", err)
+	}
+	return nil
+}
+
+// Close the underlying database file
+func (s *SqliteClient) Close() error {
+	return s.DB.Close()
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSqliteClient(t *testing.T) *SqliteClient {
+	client, err := NewSqliteClient(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// Update then read back user stats and matches
+func TestSqliteUpdateAndGet(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5678, Direction: "right"}))
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5679, Direction: "right"}))
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5680, Direction: "left"}))
+
+	found, stats, err := client.GetUserStats(ctx, 1234)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 2, stats.NumLikes)
+	assert.Equal(t, 1, stats.NumDislikes)
+
+	// Nobody liked 1234 back yet
+	found, matches, err := client.GetMatches(ctx, 1234)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Empty(t, matches.MatchList)
+}
+
+// Only users who liked each other are a match
+func TestSqliteMutualMatch(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5678, Direction: "right"}))
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5679, Direction: "right"}))
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 5678, Swipee: 1234, Direction: "right"})) // mutual
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 5679, Swipee: 1234, Direction: "left"}))  // rejected
+
+	_, matches, err := client.GetMatches(ctx, 1234)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5678}, matches.MatchList)
+
+	_, matches, err = client.GetMatches(ctx, 5678)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1234}, matches.MatchList)
+
+	_, matches, err = client.GetMatches(ctx, 5679)
+	assert.NoError(t, err)
+	assert.Empty(t, matches.MatchList)
+}
+
+// Comments go into the swipee's inbox
+func TestSqliteAddComment(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+
+	assert.NoError(t, client.ApplySwipe(ctx, Swipe{UserId: 1234, Swipee: 5678, Direction: "right", Comment: "hello"}))
+
+	var from int
+	var comment string
+	err := client.DB.QueryRow(`SELECT from_id, comment FROM comments WHERE user_id = ?`, 5678).Scan(&from, &comment)
+	assert.NoError(t, err)
+	assert.Equal(t, 1234, from)
+	assert.Equal(t, "hello", comment)
+}
+
+// A redelivered swipe is skipped, including its comment
+func TestSqliteApplySwipeOnce(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+
+	swipe := Swipe{SwipeId: "abc", UserId: 1234, Swipee: 5678, Direction: "right", Comment: "hello"}
+	assert.NoError(t, client.ApplySwipe(ctx, swipe))
+	assert.NoError(t, client.ApplySwipe(ctx, swipe))
+
+	_, stats, err := client.GetUserStats(ctx, 1234)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.NumLikes)
+
+	var comments int
+	err = client.DB.QueryRow(`SELECT COUNT(*) FROM comments WHERE user_id = ?`, 5678).Scan(&comments)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, comments)
+}
+
+// Unknown users are not found but life goes on
+func TestSqliteNotFound(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+
+	found, _, err := client.GetUserStats(ctx, 1234)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	found, _, err = client.GetMatches(ctx, 1234)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestSqliteInvalidDirection(t *testing.T) {
+	client := newTestSqliteClient(t)
+
+	err := client.ApplySwipe(context.Background(), Swipe{UserId: 1234, Swipee: 5678, Direction: "middle"})
+	assert.EqualError(t, err, "invalid swipe direction: middle")
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+)
+
+var zlog = logger.GetLogger()
+
+//go:generate mockery --name=Store --filename=mock_store.go
+type Store interface {
+	GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error)
+	GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error)
+	ApplySwipe(ctx context.Context, swipe Swipe) error
+}
+
+// How long applied swipe ids are remembered. Must outlive every redelivery and retry of a swipe.
+const defaultAppliedTTL = 24 * time.Hour
+
+// Internal function that falls back to the default ttl if ttl isn't set
+func appliedTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return defaultAppliedTTL
+	}
+	return ttl
+}
+
+// A Store that can apply many swipes in one round trip
+type BatchStore interface {
+	Store
+	UpdateUserStatsBatch(ctx context.Context, swipes []Swipe) error
+}
+
+// Returned by UpdateUserStatsBatch when only some swipes failed. Swipes not in Errs were applied.
+// A failed swipe left nothing behind except maybe its match, which is safe to add again.
+type BatchError struct {
+	Errs map[int]error // Keyed by position in the batch
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d swipes in the batch failed", len(e.Errs))
+}
+
+// A single swipe to be applied to the store. The like, stats, and comment are written together and
+// only once per SwipeId, so a redelivered swipe is skipped.
+type Swipe struct {
+	SwipeId   string // Empty for messages published before swipes had ids, those can't be deduplicated
+	UserId    int
+	Swipee    int
+	Direction string
+	Comment   string // Empty if the swiper didn't leave a comment
+}
+
+// How to connect to the database
+type Options struct {
+	Backend          string        // dynamodb, sqlite, postgres, or memory, defaults to dynamodb
+	SqlitePath       string        // SQLite database file
+	PostgresURL      string        // PostgreSQL connection string
+	SnapshotPath     string        // Where the in-memory store is snapshotted
+	SnapshotInterval time.Duration // How often the in-memory store is snapshotted
+	Region           string        // AWS region of the DynamoDB tables
+	Router           shard.Router  // DynamoDB shard layout
+	AppliedTTL       time.Duration // How long applied swipe ids are remembered, zero means a day
+}
+
+// Create a new Store for the backend in opts. Defaults to DynamoDB.
+func NewStore(opts Options) (Store, error) {
+	switch opts.Backend {
+	case "", "dynamodb":
+		client, err := NewDatabaseClient(opts.Region, opts.Router)
+		if err != nil {
+			return nil, err
+		}
+		client.AppliedTTL = opts.AppliedTTL
+		return client, nil
+	case "sqlite":
+		client, err := NewSqliteClient(opts.SqlitePath)
+		if err != nil {
+			return nil, err
+		}
+		client.AppliedTTL = opts.AppliedTTL
+		return client, nil
+	case "postgres":
+		client, err := NewPostgresClient(opts.PostgresURL)
+		if err != nil {
+			return nil, err
+		}
+		client.AppliedTTL = opts.AppliedTTL
+		return client, nil
+	case "memory":
+		client, err := NewMemoryClient(opts.SnapshotPath, opts.SnapshotInterval)
+		if err != nil {
+			return nil, err
+		}
+		client.AppliedTTL = opts.AppliedTTL
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown DB_BACKEND: %s", opts.Backend)
+	}
+}
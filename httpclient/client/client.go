@@ -65,6 +65,46 @@ func (client *ApiClient) SwipeLeftOrRight(direction string) {
 	}
 }
 
+// POST /swipes/batch
+// Counts every accepted swipe as a success and every rejected swipe as an error
+func (client *ApiClient) SwipeBatch(size int) {
+	batch := make([]models.SwipeRequest, size)
+	for i := range batch {
+		batch[i] = models.SwipeRequest{
+			Swiper:    strconv.Itoa(datagen.RandInt(client.Rng, 1, 5000)),
+			Swipee:    strconv.Itoa(datagen.RandInt(client.Rng, 1, 1_000_000)),
+			Comment:   datagen.RandComment(client.Rng, 256),
+			Direction: datagen.RandDirection(client.Rng),
+		}
+	}
+	endpoint := fmt.Sprintf("%s/swipes/batch", client.ServerUrl)
+
+	req := client.newPostRequest(endpoint, batch)
+	resp, err := client.sendRequest(req, 5)
+	if err != nil {
+		client.ErrorCount += uint64(size)
+		zlog.Error().Err(err).Str("method", "POST").Str("endpoint", endpoint).Msg("max retries hit")
+		return
+	}
+	defer resp.Body.Close()
+
+	// StatusCode should be 201 or 207, else the whole batch failed
+	var batchResp models.BatchSwipeResponse
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMultiStatus {
+		client.ErrorCount += uint64(size)
+		zlog.Warn().Str("method", "POST").Str("endpoint", endpoint).Int("code", resp.StatusCode).Msg("response")
+		return
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&batchResp); err != nil {
+		client.ErrorCount += uint64(size)
+		zlog.Error().Err(err).Str("method", "POST").Str("endpoint", endpoint).Msg("bad batch response")
+		return
+	}
+	client.SuccessCount += uint64(batchResp.Accepted)
+	client.ErrorCount += uint64(size - batchResp.Accepted)
+	zlog.Debug().Str("method", "POST").Str("endpoint", endpoint).Int("code", resp.StatusCode).Int("accepted", batchResp.Accepted).Msg("response")
+}
+
 // GET /stats/{userId}/
 func (client *ApiClient) GetUserStats() {
 	userId := datagen.RandInt(client.Rng, 1, 5000)
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/config"
+)
+
+// Settings of the load test client. Every setting can come from an env variable, the YAML file at CONFIG_FILE or
+// -config, or a flag named after its yaml key.
+type Config struct {
+	config.Logging `yaml:",inline"`
+
+	ServerURL string `yaml:"server_url" env:"SERVER_URL" required:"true" usage:"https://server_url or http://server_ip:port"`
+	Port      string `yaml:"port" env:"PORT" default:"8081" usage:"port of the health check endpoint"`
+	Workers   int    `yaml:"max_workers" env:"MAX_WORKERS" default:"50" usage:"goroutines sending swipes"`
+	Requests  int    `yaml:"num_requests" env:"NUM_REQUESTS" default:"100000" usage:"total swipes to send"`
+	BatchSize int    `yaml:"batch_size" env:"BATCH_SIZE" default:"1" usage:"swipes per POST request, values above 1 use /swipes/batch"`
+	MaxBatch  int    `yaml:"max_swipe_batch" env:"MAX_SWIPE_BATCH" default:"500" usage:"most swipes the server takes in one batch, must match the httpserver's"`
+}
+
+func (c *Config) Validate() error {
+	if c.Workers < 1 || c.Requests < 1 || c.BatchSize < 1 {
+		return errors.New("max_workers, num_requests, and batch_size must be positive")
+	}
+	if c.BatchSize > c.MaxBatch {
+		return fmt.Errorf("batch_size can't be bigger than the server's max_swipe_batch %d", c.MaxBatch)
+	}
+	return nil
+}
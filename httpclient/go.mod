@@ -1,6 +1,6 @@
 module github.com/DennisPing/cs6650-twinder-a3/httpclient
 
-go 1.19
+go 1.23.0
 
 require (
 	github.com/DennisPing/cs6650-twinder-a3/lib v0.0.0-20230618011939-067fad580a44
@@ -11,5 +11,8 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
 	github.com/rs/zerolog v1.29.1 // indirect
-	golang.org/x/sys v0.9.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/DennisPing/cs6650-twinder-a3/lib => ../lib
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,43 +12,44 @@ import (
 
 	"github.com/DennisPing/cs6650-twinder-a3/httpclient/client"
 	"github.com/DennisPing/cs6650-twinder-a3/httpclient/datagen"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/config"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
 	"github.com/montanaflynn/stats"
 )
 
-const (
-	maxWorkers  = 50
-	numRequests = 100_000
-)
-
 var zlog = logger.GetLogger()
 
 func main() {
-	serverURL := os.Getenv("SERVER_URL")
-	if serverURL == "" {
-		zlog.Fatal().Msg("SERVER_URL env variable not set")
+	var cfg Config
+	if err := config.Load(&cfg, "httpclient", os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		zlog.Fatal().Err(err).Msg("invalid config")
 	}
-
-	port := os.Getenv("PORT") // Set the PORT to 8081 for local testing
-	if port == "" {
-		port = "8081" // Running in the cloud
+	if err := cfg.SetLogLevel(); err != nil {
+		zlog.Fatal().Err(err).Msg("invalid config")
 	}
+	serverURL, batchSize, maxWorkers := cfg.ServerURL, cfg.BatchSize, cfg.Workers
 
 	// Health check endpoint
 	go func() {
 		http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 		})
-		addr := fmt.Sprintf(":%s", port)
+		addr := fmt.Sprintf(":%s", cfg.Port)
 		if err := http.ListenAndServe(addr, nil); err != nil {
 			zlog.Fatal().Err(err).Msg("health check crashed")
 		}
 	}()
 
-	// Populate the task queue with tasks (token)
-	taskQueue := make(chan struct{}, numRequests)
-	for i := 0; i < numRequests; i++ {
-		taskQueue <- struct{}{}
+	// Send swipes in batches of batch_size. A batch_size of 1 sends each swipe with POST /swipe, bigger ones use POST /swipes/batch
+	numTasks := (cfg.Requests + batchSize - 1) / batchSize
+
+	// Populate the task queue with the size of each batch. The last one only sends what's left.
+	taskQueue := make(chan int, numTasks)
+	for i := 0; i < numTasks; i++ {
+		taskQueue <- min(batchSize, cfg.Requests-i*batchSize)
 	}
 	close(taskQueue) // Close the queue. Nothing is ever being put into the queue.
 
@@ -81,7 +84,7 @@ func main() {
 
 	// Start the main actions
 	zlog.Info().Msgf("Using %d goroutines", maxWorkers)
-	zlog.Info().Msgf("Starting %d requests...", numRequests)
+	zlog.Info().Msgf("Starting %d requests with %d swipes each...", numTasks, batchSize)
 	startTime := time.Now()
 
 	var wg sync.WaitGroup
@@ -94,10 +97,14 @@ func main() {
 			apiClient := workerPool[workerId]
 
 			// Do tasks until taskQueue is empty. Then all workers can go home.
-			for range taskQueue {
-				direction := datagen.RandDirection(apiClient.Rng)
+			for size := range taskQueue {
 				t0 := time.Now()
-				apiClient.SwipeLeftOrRight(direction) // The actual HTTP request
+				if batchSize > 1 {
+					apiClient.SwipeBatch(size)
+				} else {
+					direction := datagen.RandDirection(apiClient.Rng)
+					apiClient.SwipeLeftOrRight(direction) // The actual HTTP request
+				}
 				t1 := time.Since(t0)
 				responseTimes[workerId] = append(responseTimes[workerId], t1) // Thread safe
 			}
@@ -150,9 +157,9 @@ func main() {
 
 	fmt.Println("Done!")
 	zlog.Info().Msgf("Total run time: %v", duration)
-	zlog.Info().Msgf("Throughput: %.2f req/sec", throughput)
+	zlog.Info().Msgf("Throughput: %.2f swipes/sec", throughput)
 
-	allResponseTimes := make([]float64, 0, numRequests)
+	allResponseTimes := make([]float64, 0, numTasks)
 	for _, slice := range responseTimes { // Convert all time.Duration to float64
 		for _, rt := range slice {
 			rtFloat := float64(rt.Milliseconds())
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/config"
+)
+
+// Settings of the httpserver. Every setting can come from an env variable, the YAML file at CONFIG_FILE or -config,
+// or a flag named after its yaml key.
+type Config struct {
+	config.Logging  `yaml:",inline"`
+	config.RabbitMQ `yaml:",inline"`
+	config.AWS      `yaml:",inline"`
+	config.Tracing  `yaml:",inline"`
+	config.Metrics  `yaml:",inline"`
+
+	Port     string `yaml:"port" env:"PORT" default:"80" usage:"port to listen on"`
+	ServerId string `yaml:"server_id" env:"RAILWAY_REPLICA_ID" usage:"id of this replica in the metrics"`
+
+	DBBackend   string `yaml:"db_backend" env:"DB_BACKEND" default:"dynamodb" oneof:"dynamodb,sqlite,postgres" usage:"database backend"`
+	SqlitePath  string `yaml:"sqlite_path" env:"SQLITE_PATH" default:"twinder.db" usage:"path to the SQLite file"`
+	PostgresURL string `yaml:"postgres_url" env:"POSTGRES_URL" usage:"PostgreSQL connection string, must match the consumer's"`
+	ShardConfig string `yaml:"shard_config" env:"SHARD_CONFIG" usage:"JSON shard map, must match the consumer's"`
+
+	CacheSize         int           `yaml:"cache_size" env:"CACHE_SIZE" usage:"max cached stats and matches entries, 0 disables the cache"`
+	CacheTTL          time.Duration `yaml:"cache_ttl" env:"CACHE_TTL" default:"5s" usage:"how long cache entries live"`
+	CacheInvalidation bool          `yaml:"cache_invalidation" env:"CACHE_INVALIDATION" usage:"drop cache entries when the consumer announces a write"`
+
+	PublishConfirms       bool          `yaml:"publish_confirms" env:"PUBLISH_CONFIRMS" usage:"wait for RabbitMQ to confirm every swipe"`
+	PublishConfirmTimeout time.Duration `yaml:"publish_confirm_timeout" env:"PUBLISH_CONFIRM_TIMEOUT" default:"5s" usage:"how long to wait for a confirm"`
+
+	MaxSwipeBatch int `yaml:"max_swipe_batch" env:"MAX_SWIPE_BATCH" default:"500" usage:"most swipes in one POST /swipes/batch, clients' batch_size must not be bigger"`
+
+	SpoolDir   string `yaml:"spool_dir" env:"SPOOL_DIR" usage:"directory to spool swipes to while RabbitMQ is unavailable"`
+	SpoolMaxMB int    `yaml:"spool_max_mb" env:"SPOOL_MAX_MB" default:"100" usage:"max spool size in MB"`
+}
+
+func (c *Config) Validate() error {
+	if err := c.ValidateMetrics(); err != nil {
+		return err
+	}
+	if c.CacheSize < 0 {
+		return errors.New("cache_size can't be negative")
+	}
+	if c.CacheTTL <= 0 {
+		return errors.New("cache_ttl must be positive")
+	}
+	if c.PublishConfirmTimeout <= 0 {
+		return errors.New("publish_confirm_timeout must be positive")
+	}
+	if c.MaxSwipeBatch <= 0 {
+		return errors.New("max_swipe_batch must be positive")
+	}
+	if c.SpoolMaxMB <= 0 {
+		return errors.New("spool_max_mb must be positive")
+	}
+	return nil
+}
+
+// How long to wait for publisher confirms, 0 if they're off
+func (c *Config) confirmTimeout() time.Duration {
+	if !c.PublishConfirms {
+		return 0
+	}
+	return c.PublishConfirmTimeout
+}
@@ -1,58 +1,140 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/metrics"
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/rmqconsumer"
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/rmqproducer"
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/server"
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/spool"
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/store"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/config"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
 )
 
 var zlog = logger.GetLogger()
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "80"
+	var cfg Config
+	if err := config.Load(&cfg, "httpserver", os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		zlog.Fatal().Err(err).Msg("invalid config")
+	}
+	if err := cfg.SetLogLevel(); err != nil {
+		zlog.Fatal().Err(err).Msg("invalid config")
 	}
-	addr := fmt.Sprintf(":%s", port)
+	addr := fmt.Sprintf(":%s", cfg.Port)
 
-	// Initialize metrics client
-	metricsClient, err := metrics.NewMetricsClient()
+	// Trace requests from the httpserver through RabbitMQ to the database
+	shutdownTracing, err := tracing.Setup(context.Background(), "httpserver", tracing.Options{
+		Exporter: cfg.TraceExporter,
+		Endpoint: cfg.TraceEndpoint,
+	})
 	if err != nil {
-		zlog.Fatal().Err(err).Msg("unable to set up metrics")
+		zlog.Fatal().Err(err).Msg("unable to set up tracing")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			zlog.Error().Err(err).Msg("failed to flush traces")
+		}
+	}()
+
+	// Initialize metrics client
+	var metricsClient metrics.Metrics
+	if cfg.MetricsBackend == "prometheus" {
+		metricsClient = metrics.NewPrometheusMetrics()
+	} else {
+		axiomClient, err := metrics.NewMetricsClient(cfg.ServerId, cfg.AxiomToken, cfg.AxiomDataset)
+		if err != nil {
+			zlog.Fatal().Err(err).Msg("unable to set up metrics")
+		}
+		metricsClient = axiomClient
 	}
 
 	// Initialize rabbitmq publisher
-	rmqConn, err := rmqproducer.NewConnection()
+	rmqConn, err := rmqproducer.NewConnection(cfg.RabbitURL())
 	if err != nil {
-		zlog.Fatal().Err(err).Msg("unable to make rabbitmq connection")
+		zlog.Fatal().Err(err).Stringer("rabbitmq", cfg.RabbitMQ).Msg("unable to make rabbitmq connection")
 	}
 	defer rmqConn.Close()
-	publisher, err := rmqproducer.NewPublisher(rmqConn)
+	publisher, err := rmqproducer.NewPublisher(rmqConn, cfg.ExchangeKind, cfg.PublishConfirms)
 	if err != nil {
 		zlog.Fatal().Err(err).Msg("unable to make rabbitmq publisher")
 	}
 	defer publisher.Close()
 
+	router, err := shard.NewRouterFromFile(cfg.ShardConfig)
+	if err != nil {
+		zlog.Fatal().Err(err).Msg("invalid shard config")
+	}
+
 	// Initialize database client
-	dbClient, err := store.NewDatabaseClient()
+	dbClient, err := store.NewStore(store.Options{
+		Backend:     cfg.DBBackend,
+		SqlitePath:  cfg.SqlitePath,
+		PostgresURL: cfg.PostgresURL,
+		Region:      cfg.AWSRegion,
+		Router:      router,
+	})
 	if err != nil {
-		zlog.Fatal().Err(err).Msg("unable to connect to DynamoDB")
+		zlog.Fatal().Err(err).Msg("unable to connect to database")
+	}
+	if closer, ok := dbClient.(io.Closer); ok {
+		defer closer.Close()
+	}
+	zlog.Info().Msg("connected to database")
+
+	// Cache stats and matches in front of the database
+	if cfg.CacheSize > 0 {
+		cachedStore := store.NewCachedStore(dbClient, cfg.CacheSize, cfg.CacheTTL, metricsClient)
+		if cfg.CacheInvalidation {
+			invalidations, err := rmqconsumer.NewInvalidationConsumer(rmqConn, cachedStore.Invalidate)
+			if err != nil {
+				zlog.Fatal().Err(err).Msg("unable to subscribe to cache invalidations")
+			}
+			defer invalidations.Close()
+		}
+		dbClient = cachedStore
 	}
-	zlog.Info().Msg("connected to DynamoDB")
 
 	// Initialize the http server
 	server := server.NewServer(addr, metricsClient, publisher, dbClient)
+	server.ConfirmTimeout = cfg.confirmTimeout()
+	server.Router = router
+	server.MaxSwipeBatch = cfg.MaxSwipeBatch
+	server.MetricsInterval = cfg.MetricsInterval
+
+	// Spool swipes to disk while RabbitMQ is unavailable
+	if cfg.SpoolDir != "" {
+		swipeSpool, err := spool.Open(cfg.SpoolDir, int64(cfg.SpoolMaxMB)<<20)
+		if err != nil {
+			zlog.Fatal().Err(err).Msg("unable to open spool")
+		}
+		defer swipeSpool.Close()
+		if depth := swipeSpool.Depth(); depth > 0 {
+			zlog.Info().Int("depth", depth).Msg("replaying spooled swipes from last run")
+		}
+		server.Spool = swipeSpool
+	}
 
 	// Run the http server in a goroutine
-	fmt.Printf("Starting server on port %s...\n", port)
+	fmt.Printf("Starting server on port %s...\n", cfg.Port)
 	go func() {
 		if err := server.Start(); err != nil && err != http.ErrServerClosed {
 			zlog.Fatal().Err(err).Msg("HTTP server died")
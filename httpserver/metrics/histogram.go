@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	histogramMin     = 50 * time.Microsecond // Anything faster lands in the first bucket
+	bucketsPerDouble = 8                     // Each bucket is about 9% wider than the one before
+	histogramBuckets = 21 * bucketsPerDouble // The last bucket takes everything slower than about 90s
+)
+
+// A fixed size latency histogram with exponential buckets, so a busy route takes as much memory as a quiet one.
+// Percentiles are within about 9% of the real latency.
+type histogram struct {
+	counts [histogramBuckets]uint64
+	count  uint64
+	max    time.Duration
+}
+
+// Add a latency to the histogram
+func (h *histogram) record(latency time.Duration) {
+	h.counts[bucketOf(latency)]++
+	h.count++
+	if latency > h.max {
+		h.max = latency
+	}
+}
+
+// The nearest-rank percentile, rounded up to the end of its bucket but never past the max
+func (h *histogram) percentile(p int) time.Duration {
+	rank := (h.count*uint64(p) + 99) / 100 // ceil(n * p / 100)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			return min(upperBound(i), h.max)
+		}
+	}
+	return h.max
+}
+
+// The bucket a latency is counted in. Bucket i holds latencies up to upperBound(i).
+func bucketOf(latency time.Duration) int {
+	if latency <= histogramMin {
+		return 0
+	}
+	i := int(math.Ceil(math.Log2(float64(latency)/float64(histogramMin)) * bucketsPerDouble))
+	return min(i, histogramBuckets-1)
+}
+
+// The largest latency in bucket i
+func upperBound(i int) time.Duration {
+	if i == histogramBuckets-1 {
+		return math.MaxInt64
+	}
+	return time.Duration(float64(histogramMin) * math.Exp2(float64(i)/bucketsPerDouble))
+}
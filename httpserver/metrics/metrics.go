@@ -2,49 +2,60 @@ package metrics
 
 import (
 	"context"
-	"errors"
-	"os"
 	"sync"
 	"time"
 
+	libmetrics "github.com/DennisPing/cs6650-twinder-a3/lib/metrics"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
 	"github.com/axiomhq/axiom-go/axiom"
-	"github.com/axiomhq/axiom-go/axiom/ingest"
 )
 
 //go:generate mockery --name=Metrics --filename=mock_metrics.go
 type Metrics interface {
+	libmetrics.Metrics
 	IncrementThroughput()
 	GetThroughput() uint64
-	SendMetrics() error
+	IncrementCacheHit()
+	IncrementCacheMiss()
+	RecordRequest(route, method string, status int, latency time.Duration)
+	RecordPublish(latency time.Duration, err error)
+	RecordConfirmLatency(latency time.Duration)
+	SetSpoolDepth(depth int)
 }
 
 type AxiomMetrics struct {
-	client      *axiom.Client
+	*libmetrics.Axiom
+	requests    map[routeKey]*histogram // Request latencies by route since the last send
 	ServerId    string
-	DatasetName string
 	Throughput  uint64
+	CacheHits   uint64
+	CacheMisses uint64
+	Publishes   uint64        // Swipes handed to RabbitMQ
+	PublishErrs uint64        // Publishes RabbitMQ refused
+	PublishTime time.Duration // Total time spent publishing
+	Confirms    uint64        // Publisher confirms received
+	ConfirmTime time.Duration // Total time spent waiting for them
+	ConfirmMax  time.Duration
+	SpoolDepth  int // Swipes waiting on disk for RabbitMQ to come back
 	Mutex       sync.Mutex
 }
 
-// Create a new AxiomMetrics client which implements the Metrics interface
-func NewMetricsClient() (*AxiomMetrics, error) {
-	serverId := os.Getenv("RAILWAY_REPLICA_ID")
-	apiToken := os.Getenv("AXIOM_API_TOKEN")
-	datasetName := os.Getenv("AXIOM_DATASET")
+// The route, method, and status code a request is counted under
+type routeKey struct {
+	route  string
+	method string
+	status int
+}
 
-	if apiToken == "" || datasetName == "" {
-		return nil, errors.New("you forgot to set the AXIOM env variables")
-	}
-	client, err := axiom.NewClient(
-		axiom.SetAPITokenConfig(apiToken),
-	)
+// Create a new AxiomMetrics client which implements the Metrics interface
+func NewMetricsClient(serverId, apiToken, datasetName string) (*AxiomMetrics, error) {
+	client, err := libmetrics.NewAxiom(apiToken, datasetName)
 	if err != nil {
 		return nil, err
 	}
 	return &AxiomMetrics{
-		client:      client,
-		ServerId:    serverId,
-		DatasetName: datasetName,
+		Axiom:    client,
+		ServerId: serverId,
 	}, nil
 }
 
@@ -64,15 +75,135 @@ func (m *AxiomMetrics) GetThroughput() uint64 {
 	return throughput
 }
 
-// Send the metrics over to Axiom
-func (m *AxiomMetrics) SendMetrics() error {
+// Increment the cache hit count
+func (m *AxiomMetrics) IncrementCacheHit() {
+	m.Mutex.Lock()
+	m.CacheHits++
+	m.Mutex.Unlock()
+}
+
+// Increment the cache miss count
+func (m *AxiomMetrics) IncrementCacheMiss() {
+	m.Mutex.Lock()
+	m.CacheMisses++
+	m.Mutex.Unlock()
+}
+
+// Record the count and latency of a request
+func (m *AxiomMetrics) RecordRequest(route, method string, status int, latency time.Duration) {
+	key := routeKey{route: route, method: method, status: status}
+	m.Mutex.Lock()
+	if m.requests == nil {
+		m.requests = make(map[routeKey]*histogram)
+	}
+	h, ok := m.requests[key]
+	if !ok {
+		h = &histogram{}
+		m.requests[key] = h
+	}
+	h.record(latency)
+	m.Mutex.Unlock()
+}
+
+// Return the stats of every route since the last call and reset them
+func (m *AxiomMetrics) routeStats(now time.Time) []models.ServerRouteStats {
+	m.Mutex.Lock()
+	requests := m.requests
+	m.requests = nil
+	m.Mutex.Unlock()
+
+	stats := make([]models.ServerRouteStats, 0, len(requests))
+	for key, h := range requests {
+		stats = append(stats, models.ServerRouteStats{
+			Time:         now.Format(time.RFC3339Nano),
+			ServerId:     m.ServerId,
+			Route:        key.route,
+			Method:       key.method,
+			Status:       key.status,
+			Count:        h.count,
+			LatencyP50Ms: toMs(h.percentile(50)),
+			LatencyP95Ms: toMs(h.percentile(95)),
+			LatencyP99Ms: toMs(h.percentile(99)),
+			LatencyMaxMs: toMs(h.max),
+		})
+	}
+	return stats
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Record how long a publish took and whether it failed
+func (m *AxiomMetrics) RecordPublish(latency time.Duration, err error) {
+	m.Mutex.Lock()
+	m.Publishes++
+	m.PublishTime += latency
+	if err != nil {
+		m.PublishErrs++
+	}
+	m.Mutex.Unlock()
+}
+
+// Record how long the broker took to confirm a publish
+func (m *AxiomMetrics) RecordConfirmLatency(latency time.Duration) {
+	m.Mutex.Lock()
+	m.Confirms++
+	m.ConfirmTime += latency
+	if latency > m.ConfirmMax {
+		m.ConfirmMax = latency
+	}
+	m.Mutex.Unlock()
+}
+
+// Set the number of spooled swipes
+func (m *AxiomMetrics) SetSpoolDepth(depth int) {
+	m.Mutex.Lock()
+	m.SpoolDepth = depth
+	m.Mutex.Unlock()
+}
+
+// Return the server stats since the last call and reset the counts
+func (m *AxiomMetrics) snapshot(now time.Time) models.ServerThroughput {
 	throughput := m.GetThroughput()
-	ctx := context.Background()
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+	stats := models.ServerThroughput{
+		Time:                now.Format(time.RFC3339Nano),
+		ServerId:            m.ServerId,
+		Throughput:          throughput,
+		CacheHits:           m.CacheHits,
+		CacheMisses:         m.CacheMisses,
+		PublishErrors:       m.PublishErrs,
+		ConfirmLatencyMaxMs: m.ConfirmMax.Milliseconds(),
+		SpoolDepth:          m.SpoolDepth,
+	}
+	if m.Publishes > 0 {
+		stats.PublishLatencyAvgMs = float64(m.PublishTime) / float64(m.Publishes) / float64(time.Millisecond)
+	}
+	if m.Confirms > 0 {
+		stats.ConfirmLatencyAvgMs = float64(m.ConfirmTime) / float64(m.Confirms) / float64(time.Millisecond)
+	}
+	m.CacheHits, m.CacheMisses = 0, 0
+	m.Publishes, m.PublishErrs, m.PublishTime = 0, 0, 0
+	m.Confirms, m.ConfirmTime, m.ConfirmMax = 0, 0, 0
+	return stats
+}
 
-	if _, err := m.client.IngestEvents(ctx, m.DatasetName, []axiom.Event{
-		{ingest.TimestampField: time.Now(), "ServerId": m.ServerId, "Throughput": throughput},
-	}); err != nil {
+// Send the metrics over to Axiom. Every event uses the same camelCase keys as its model's json tags.
+func (m *AxiomMetrics) SendMetrics() error {
+	now := time.Now()
+	event, err := libmetrics.ToEvent(m.snapshot(now))
+	if err != nil {
 		return err
 	}
-	return nil
+	events := []axiom.Event{event}
+	for _, stats := range m.routeStats(now) {
+		event, err := libmetrics.ToEvent(stats)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	return m.Ingest(context.Background(), events...)
 }
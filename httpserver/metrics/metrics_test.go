@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	libmetrics "github.com/DennisPing/cs6650-twinder-a3/lib/metrics"
+	"github.com/axiomhq/axiom-go/axiom/ingest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	var latencies histogram
+	for i := 1; i <= 100; i++ {
+		latencies.record(time.Duration(i) * time.Millisecond)
+	}
+	var single histogram
+	single.record(time.Millisecond)
+	var slow histogram
+	slow.record(10 * time.Minute) // Past the last bucket
+
+	tests := []struct {
+		name string
+		h    *histogram
+		p    int
+		want time.Duration
+	}{
+		{"p50", &latencies, 50, 50 * time.Millisecond},
+		{"p95", &latencies, 95, 95 * time.Millisecond},
+		{"p99", &latencies, 99, 99 * time.Millisecond},
+		{"single request", &single, 99, time.Millisecond},
+		{"slower than every bucket", &slow, 50, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.h.percentile(tt.p)
+			assert.GreaterOrEqual(t, got, tt.want) // Rounded up to the end of the bucket
+			assert.InEpsilon(t, float64(tt.want), float64(got), 0.1)
+		})
+	}
+	assert.Equal(t, uint64(100), latencies.count)
+	assert.Equal(t, 100*time.Millisecond, latencies.max)
+}
+
+// The server event has the same camelCase keys as the route events
+func TestAxiomSnapshot(t *testing.T) {
+	m := &AxiomMetrics{ServerId: "s1"}
+	m.IncrementThroughput()
+	m.IncrementCacheHit()
+	m.RecordPublish(2*time.Millisecond, nil)
+	m.RecordPublish(4*time.Millisecond, errors.New("channel closed"))
+	m.RecordConfirmLatency(5 * time.Millisecond)
+	m.SetSpoolDepth(3)
+
+	event, err := libmetrics.ToEvent(m.snapshot(time.Now()))
+	require.NoError(t, err)
+	assert.Equal(t, "s1", event["serverId"])
+	assert.NotEmpty(t, event[ingest.TimestampField])
+	assert.Equal(t, 1.0, event["throughput"])
+	assert.Equal(t, 1.0, event["cacheHits"])
+	assert.Equal(t, 3.0, event["publishLatencyAvgMs"])
+	assert.Equal(t, 1.0, event["publishErrors"])
+	assert.Equal(t, 5.0, event["confirmLatencyMaxMs"])
+	assert.Equal(t, 3.0, event["spoolDepth"])
+
+	stats := m.snapshot(time.Now()) // The counts reset but the spool depth gauge doesn't
+	assert.Zero(t, stats.Throughput)
+	assert.Zero(t, stats.PublishErrors)
+	assert.Equal(t, 3, stats.SpoolDepth)
+}
+
+// Requests are grouped by route, method, and status, and reset after every send
+func TestAxiomRouteStats(t *testing.T) {
+	m := &AxiomMetrics{ServerId: "s1"}
+	for _, ms := range []int{3, 1, 2} {
+		m.RecordRequest("/stats/{userId}/", http.MethodGet, http.StatusOK, time.Duration(ms)*time.Millisecond)
+	}
+	m.RecordRequest("/stats/{userId}/", http.MethodGet, http.StatusNotFound, time.Millisecond)
+
+	stats := m.routeStats(time.Now())
+	require.Len(t, stats, 2)
+	if stats[0].Status != http.StatusOK {
+		stats[0], stats[1] = stats[1], stats[0]
+	}
+	assert.Equal(t, "s1", stats[0].ServerId)
+	assert.Equal(t, "/stats/{userId}/", stats[0].Route)
+	assert.Equal(t, http.MethodGet, stats[0].Method)
+	assert.Equal(t, uint64(3), stats[0].Count)
+	assert.InEpsilon(t, 2.0, stats[0].LatencyP50Ms, 0.1)
+	assert.Equal(t, 3.0, stats[0].LatencyP99Ms)
+	assert.Equal(t, 3.0, stats[0].LatencyMaxMs)
+	assert.Equal(t, uint64(1), stats[1].Count)
+
+	assert.Empty(t, m.routeStats(time.Now()))
+}
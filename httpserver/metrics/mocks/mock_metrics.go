@@ -2,7 +2,11 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Metrics is an autogenerated mock type for the Metrics type
 type Metrics struct {
@@ -58,6 +62,70 @@ func (_c *Metrics_GetThroughput_Call) RunAndReturn(run func() uint64) *Metrics_G
 	return _c
 }
 
+// IncrementCacheHit provides a mock function with given fields:
+func (_m *Metrics) IncrementCacheHit() {
+	_m.Called()
+}
+
+// Metrics_IncrementCacheHit_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IncrementCacheHit'
+type Metrics_IncrementCacheHit_Call struct {
+	*mock.Call
+}
+
+// IncrementCacheHit is a helper method to define mock.On call
+func (_e *Metrics_Expecter) IncrementCacheHit() *Metrics_IncrementCacheHit_Call {
+	return &Metrics_IncrementCacheHit_Call{Call: _e.mock.On("IncrementCacheHit")}
+}
+
+func (_c *Metrics_IncrementCacheHit_Call) Run(run func()) *Metrics_IncrementCacheHit_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Metrics_IncrementCacheHit_Call) Return() *Metrics_IncrementCacheHit_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_IncrementCacheHit_Call) RunAndReturn(run func()) *Metrics_IncrementCacheHit_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// IncrementCacheMiss provides a mock function with given fields:
+func (_m *Metrics) IncrementCacheMiss() {
+	_m.Called()
+}
+
+// Metrics_IncrementCacheMiss_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'IncrementCacheMiss'
+type Metrics_IncrementCacheMiss_Call struct {
+	*mock.Call
+}
+
+// IncrementCacheMiss is a helper method to define mock.On call
+func (_e *Metrics_Expecter) IncrementCacheMiss() *Metrics_IncrementCacheMiss_Call {
+	return &Metrics_IncrementCacheMiss_Call{Call: _e.mock.On("IncrementCacheMiss")}
+}
+
+func (_c *Metrics_IncrementCacheMiss_Call) Run(run func()) *Metrics_IncrementCacheMiss_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Metrics_IncrementCacheMiss_Call) Return() *Metrics_IncrementCacheMiss_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_IncrementCacheMiss_Call) RunAndReturn(run func()) *Metrics_IncrementCacheMiss_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // IncrementThroughput provides a mock function with given fields:
 func (_m *Metrics) IncrementThroughput() {
 	_m.Called()
@@ -90,6 +158,109 @@ func (_c *Metrics_IncrementThroughput_Call) RunAndReturn(run func()) *Metrics_In
 	return _c
 }
 
+// RecordConfirmLatency provides a mock function with given fields: latency
+func (_m *Metrics) RecordConfirmLatency(latency time.Duration) {
+	_m.Called(latency)
+}
+
+// Metrics_RecordConfirmLatency_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordConfirmLatency'
+type Metrics_RecordConfirmLatency_Call struct {
+	*mock.Call
+}
+
+// RecordConfirmLatency is a helper method to define mock.On call
+//   - latency time.Duration
+func (_e *Metrics_Expecter) RecordConfirmLatency(latency interface{}) *Metrics_RecordConfirmLatency_Call {
+	return &Metrics_RecordConfirmLatency_Call{Call: _e.mock.On("RecordConfirmLatency", latency)}
+}
+
+func (_c *Metrics_RecordConfirmLatency_Call) Run(run func(latency time.Duration)) *Metrics_RecordConfirmLatency_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Metrics_RecordConfirmLatency_Call) Return() *Metrics_RecordConfirmLatency_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_RecordConfirmLatency_Call) RunAndReturn(run func(time.Duration)) *Metrics_RecordConfirmLatency_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecordPublish provides a mock function with given fields: latency, err
+func (_m *Metrics) RecordPublish(latency time.Duration, err error) {
+	_m.Called(latency, err)
+}
+
+// Metrics_RecordPublish_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordPublish'
+type Metrics_RecordPublish_Call struct {
+	*mock.Call
+}
+
+// RecordPublish is a helper method to define mock.On call
+//   - latency time.Duration
+//   - err error
+func (_e *Metrics_Expecter) RecordPublish(latency interface{}, err interface{}) *Metrics_RecordPublish_Call {
+	return &Metrics_RecordPublish_Call{Call: _e.mock.On("RecordPublish", latency, err)}
+}
+
+func (_c *Metrics_RecordPublish_Call) Run(run func(latency time.Duration, err error)) *Metrics_RecordPublish_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(time.Duration), args[1].(error))
+	})
+	return _c
+}
+
+func (_c *Metrics_RecordPublish_Call) Return() *Metrics_RecordPublish_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_RecordPublish_Call) RunAndReturn(run func(time.Duration, error)) *Metrics_RecordPublish_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RecordRequest provides a mock function with given fields: route, method, status, latency
+func (_m *Metrics) RecordRequest(route string, method string, status int, latency time.Duration) {
+	_m.Called(route, method, status, latency)
+}
+
+// Metrics_RecordRequest_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RecordRequest'
+type Metrics_RecordRequest_Call struct {
+	*mock.Call
+}
+
+// RecordRequest is a helper method to define mock.On call
+//   - route string
+//   - method string
+//   - status int
+//   - latency time.Duration
+func (_e *Metrics_Expecter) RecordRequest(route interface{}, method interface{}, status interface{}, latency interface{}) *Metrics_RecordRequest_Call {
+	return &Metrics_RecordRequest_Call{Call: _e.mock.On("RecordRequest", route, method, status, latency)}
+}
+
+func (_c *Metrics_RecordRequest_Call) Run(run func(route string, method string, status int, latency time.Duration)) *Metrics_RecordRequest_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(string), args[2].(int), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Metrics_RecordRequest_Call) Return() *Metrics_RecordRequest_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_RecordRequest_Call) RunAndReturn(run func(string, string, int, time.Duration)) *Metrics_RecordRequest_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SendMetrics provides a mock function with given fields:
 func (_m *Metrics) SendMetrics() error {
 	ret := _m.Called()
@@ -131,6 +302,39 @@ func (_c *Metrics_SendMetrics_Call) RunAndReturn(run func() error) *Metrics_Send
 	return _c
 }
 
+// SetSpoolDepth provides a mock function with given fields: depth
+func (_m *Metrics) SetSpoolDepth(depth int) {
+	_m.Called(depth)
+}
+
+// Metrics_SetSpoolDepth_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetSpoolDepth'
+type Metrics_SetSpoolDepth_Call struct {
+	*mock.Call
+}
+
+// SetSpoolDepth is a helper method to define mock.On call
+//   - depth int
+func (_e *Metrics_Expecter) SetSpoolDepth(depth interface{}) *Metrics_SetSpoolDepth_Call {
+	return &Metrics_SetSpoolDepth_Call{Call: _e.mock.On("SetSpoolDepth", depth)}
+}
+
+func (_c *Metrics_SetSpoolDepth_Call) Run(run func(depth int)) *Metrics_SetSpoolDepth_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(int))
+	})
+	return _c
+}
+
+func (_c *Metrics_SetSpoolDepth_Call) Return() *Metrics_SetSpoolDepth_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Metrics_SetSpoolDepth_Call) RunAndReturn(run func(int)) *Metrics_SetSpoolDepth_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 type mockConstructorTestingTNewMetrics interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	libmetrics "github.com/DennisPing/cs6650-twinder-a3/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics client that exposes the metrics to a Prometheus scraper
+type PrometheusMetrics struct {
+	*libmetrics.Prometheus
+	requests       *prometheus.CounterVec
+	requestLatency *prometheus.HistogramVec
+	swipes         prometheus.Counter
+	cacheHits      prometheus.Counter
+	cacheMisses    prometheus.Counter
+	publishErrors  prometheus.Counter
+	publishLatency prometheus.Histogram
+	confirmLatency prometheus.Histogram
+	spoolDepth     prometheus.Gauge
+	throughput     uint64 // Since the last GetThroughput
+	mutex          sync.Mutex
+}
+
+// Create a new PrometheusMetrics client which implements the Metrics and Exporter interfaces
+func NewPrometheusMetrics() *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "twinder_http_requests_total",
+			Help: "HTTP requests by route, method, and status code",
+		}, []string{"route", "method", "code"}),
+		requestLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "twinder_http_request_duration_seconds",
+			Help:    "HTTP request latency by route, method, and status code",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+		swipes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "twinder_swipes_total",
+			Help: "Swipes accepted",
+		}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "twinder_cache_hits_total",
+			Help: "Stats and matches served from the cache",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "twinder_cache_misses_total",
+			Help: "Stats and matches read from the database",
+		}),
+		publishErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "twinder_rabbitmq_publish_errors_total",
+			Help: "Swipes RabbitMQ refused to take",
+		}),
+		publishLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "twinder_rabbitmq_publish_duration_seconds",
+			Help:    "Time to hand a swipe to RabbitMQ",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}),
+		confirmLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "twinder_rabbitmq_confirm_duration_seconds",
+			Help:    "Time RabbitMQ took to confirm a publish",
+			Buckets: prometheus.DefBuckets,
+		}),
+		spoolDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "twinder_spool_depth",
+			Help: "Swipes waiting on disk for RabbitMQ to come back",
+		}),
+	}
+	m.Prometheus = libmetrics.NewPrometheus(
+		m.requests, m.requestLatency, m.swipes, m.cacheHits, m.cacheMisses,
+		m.publishErrors, m.publishLatency, m.confirmLatency, m.spoolDepth,
+	)
+	return m
+}
+
+// Increment the throughput count
+func (m *PrometheusMetrics) IncrementThroughput() {
+	m.swipes.Inc()
+	m.mutex.Lock()
+	m.throughput++
+	m.mutex.Unlock()
+}
+
+// Return the throughput and reset the count. The swipes counter keeps counting.
+func (m *PrometheusMetrics) GetThroughput() uint64 {
+	m.mutex.Lock()
+	throughput := m.throughput
+	m.throughput = 0
+	m.mutex.Unlock()
+	return throughput
+}
+
+// Increment the cache hit count
+func (m *PrometheusMetrics) IncrementCacheHit() {
+	m.cacheHits.Inc()
+}
+
+// Increment the cache miss count
+func (m *PrometheusMetrics) IncrementCacheMiss() {
+	m.cacheMisses.Inc()
+}
+
+// Record the count and latency of a request
+func (m *PrometheusMetrics) RecordRequest(route, method string, status int, latency time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestLatency.WithLabelValues(route, method, code).Observe(latency.Seconds())
+}
+
+// Record how long a publish took and whether it failed
+func (m *PrometheusMetrics) RecordPublish(latency time.Duration, err error) {
+	m.publishLatency.Observe(latency.Seconds())
+	if err != nil {
+		m.publishErrors.Inc()
+	}
+}
+
+// Record how long the broker took to confirm a publish
+func (m *PrometheusMetrics) RecordConfirmLatency(latency time.Duration) {
+	m.confirmLatency.Observe(latency.Seconds())
+}
+
+// Set the number of spooled swipes
+func (m *PrometheusMetrics) SetSpoolDepth(depth int) {
+	m.spoolDepth.Set(float64(depth))
+}
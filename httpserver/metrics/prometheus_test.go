@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	m := NewPrometheusMetrics()
+	m.RecordRequest("/stats/{userId}/", http.MethodGet, http.StatusOK, time.Millisecond)
+	m.RecordRequest("/stats/{userId}/", http.MethodGet, http.StatusOK, time.Millisecond)
+	m.RecordRequest("unmatched", http.MethodGet, http.StatusNotFound, time.Millisecond)
+	m.RecordRequest("/swipe/{leftorright}/", http.MethodPost, http.StatusCreated, time.Millisecond)
+	m.IncrementThroughput()
+	m.IncrementCacheHit()
+	m.RecordPublish(time.Millisecond, nil)
+	m.RecordPublish(time.Millisecond, errors.New("channel closed"))
+	m.RecordConfirmLatency(time.Millisecond)
+	m.SetSpoolDepth(3)
+	assert.Equal(t, uint64(1), m.GetThroughput())
+	assert.Equal(t, uint64(0), m.GetThroughput())
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+
+	for _, want := range []string{
+		`twinder_http_requests_total{code="200",method="GET",route="/stats/{userId}/"} 2`,
+		`twinder_http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+		`twinder_http_requests_total{code="201",method="POST",route="/swipe/{leftorright}/"} 1`,
+		`twinder_http_request_duration_seconds_count{code="200",method="GET",route="/stats/{userId}/"} 2`,
+		`twinder_swipes_total 1`,
+		`twinder_cache_hits_total 1`,
+		`twinder_rabbitmq_publish_errors_total 1`,
+		`twinder_rabbitmq_publish_duration_seconds_count 2`,
+		`twinder_rabbitmq_confirm_duration_seconds_count 1`,
+		`twinder_spool_depth 3`,
+	} {
+		assert.Contains(t, string(body), want)
+	}
+}
@@ -4,24 +4,68 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/metrics"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
+	"github.com/go-chi/chi"
 	"github.com/rs/zerolog/hlog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var zlog = logger.GetLogger()
 
-func LoggingMiddleware(next http.Handler) http.Handler {
-	h := hlog.NewHandler(zlog)
+// Log and trace every request, and record its count and latency by chi route pattern, method, and status code.
+// A traceparent header from the caller continues its trace.
+func LoggingMiddleware(metricsClient metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := hlog.NewHandler(zlog)
 
-	accessHandler := hlog.AccessHandler(
-		func(r *http.Request, status, size int, duration time.Duration) {
-			hlog.FromRequest(r).Info().
-				Str("method", r.Method).
-				Stringer("url", r.URL).
-				Int("code", status).
-				Dur("response_time", duration).
-				Msg("request")
+		accessHandler := hlog.AccessHandler(
+			func(r *http.Request, status, size int, duration time.Duration) {
+				if status == 0 {
+					status = http.StatusOK // The handler never wrote anything
+				}
+				route := routePattern(r)
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("http.route", route),
+					attribute.Int("http.response.status_code", status),
+				)
+				if status >= http.StatusInternalServerError {
+					span.SetStatus(codes.Error, http.StatusText(status))
+				}
+				event := hlog.FromRequest(r).Info().
+					Str("method", r.Method).
+					Stringer("url", r.URL).
+					Int("code", status).
+					Dur("response_time", duration)
+				if span.SpanContext().IsValid() { // Tracing is on or the caller sent a traceparent
+					event = event.Stringer("trace_id", span.SpanContext().TraceID())
+				}
+				event.Msg("request")
+				metricsClient.RecordRequest(route, r.Method, status, duration)
+			})
+
+		handler := h(accessHandler(next))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracing.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer)) // Renamed once the route is known
+			defer span.End()
+			handler.ServeHTTP(w, r.WithContext(ctx))
 		})
+	}
+}
 
-	return h(accessHandler(next))
+// The chi route pattern of the request, so /stats/1/ and /stats/2/ are both /stats/{userId}/
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unmatched" // Unknown paths would make a new route each
 }
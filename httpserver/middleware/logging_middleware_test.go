@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/metrics/mocks"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Requests are recorded by route pattern, so every userId counts as the same route
+func TestLoggingMiddleware(t *testing.T) {
+	mockMetrics := mocks.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest("/stats/{userId}/", http.MethodGet, http.StatusOK, mock.Anything).Return().Twice()
+	mockMetrics.EXPECT().RecordRequest("/swipe/{leftorright}/", http.MethodPost, http.StatusCreated, mock.Anything).Return().Once()
+	mockMetrics.EXPECT().RecordRequest("unmatched", http.MethodGet, http.StatusNotFound, mock.Anything).Return().Once()
+
+	router := chi.NewRouter()
+	router.Use(LoggingMiddleware(mockMetrics))
+	router.Get("/stats/{userId}/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}")) // Implicit 200
+	})
+	router.Post("/swipe/{leftorright}/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, path := range []string{"/stats/1/", "/stats/2/", "/nope"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/swipe/left/", nil))
+}
+
+// Every request gets a server span named after its route, continuing the caller's trace
+func TestLoggingMiddlewareTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	mockMetrics := mocks.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+	router := chi.NewRouter()
+	router.Use(LoggingMiddleware(mockMetrics))
+	router.Get("/stats/{userId}/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/1/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /stats/{userId}/", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Contains(t, span.Attributes(), attribute.String("http.route", "/stats/{userId}/"))
+	assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusInternalServerError))
+	assert.Equal(t, codes.Error, span.Status().Code)
+}
@@ -0,0 +1,36 @@
+package rmqconsumer
+
+import (
+	"encoding/json"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/wagslane/go-rabbitmq"
+)
+
+var zlog = logger.GetLogger()
+
+// Create a new consumer that calls invalidate for every userId the consumer announces on the "invalidations" exchange
+func NewInvalidationConsumer(conn *rabbitmq.Conn, invalidate func(userId int)) (*rabbitmq.Consumer, error) {
+	return rabbitmq.NewConsumer(
+		conn,
+		func(d rabbitmq.Delivery) rabbitmq.Action {
+			var msg models.CacheInvalidation
+			if err := json.Unmarshal(d.Body, &msg); err != nil {
+				zlog.Error().Err(err).Msg("bad cache invalidation")
+				return rabbitmq.NackDiscard
+			}
+			for _, userId := range msg.UserIds {
+				invalidate(userId)
+			}
+			return rabbitmq.Ack
+		},
+		"",
+		rabbitmq.WithConsumerOptionsLogging,
+		rabbitmq.WithConsumerOptionsRoutingKey(""),
+		rabbitmq.WithConsumerOptionsExchangeDeclare,
+		rabbitmq.WithConsumerOptionsExchangeName("invalidations"),
+		rabbitmq.WithConsumerOptionsExchangeKind("fanout"),
+		rabbitmq.WithConsumerOptionsQueueAutoDelete, // Every httpserver gets its own queue
+	)
+}
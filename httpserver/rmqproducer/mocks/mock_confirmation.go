@@ -0,0 +1,89 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Confirmation is an autogenerated mock type for the Confirmation type
+type Confirmation struct {
+	mock.Mock
+}
+
+type Confirmation_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Confirmation) EXPECT() *Confirmation_Expecter {
+	return &Confirmation_Expecter{mock: &_m.Mock}
+}
+
+// WaitContext provides a mock function with given fields: ctx
+func (_m *Confirmation) WaitContext(ctx context.Context) (bool, error) {
+	ret := _m.Called(ctx)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (bool, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) bool); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Confirmation_WaitContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WaitContext'
+type Confirmation_WaitContext_Call struct {
+	*mock.Call
+}
+
+// WaitContext is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Confirmation_Expecter) WaitContext(ctx interface{}) *Confirmation_WaitContext_Call {
+	return &Confirmation_WaitContext_Call{Call: _e.mock.On("WaitContext", ctx)}
+}
+
+func (_c *Confirmation_WaitContext_Call) Run(run func(ctx context.Context)) *Confirmation_WaitContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Confirmation_WaitContext_Call) Return(_a0 bool, _a1 error) *Confirmation_WaitContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Confirmation_WaitContext_Call) RunAndReturn(run func(context.Context) (bool, error)) *Confirmation_WaitContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+type mockConstructorTestingTNewConfirmation interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewConfirmation creates a new instance of Confirmation. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewConfirmation(t mockConstructorTestingTNewConfirmation) *Confirmation {
+	mock := &Confirmation{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -3,8 +3,12 @@
 package mocks
 
 import (
+	context "context"
+
 	mock "github.com/stretchr/testify/mock"
 	rabbitmq "github.com/wagslane/go-rabbitmq"
+
+	rmqproducer "github.com/DennisPing/cs6650-twinder-a3/httpserver/rmqproducer"
 )
 
 // Publisher is an autogenerated mock type for the Publisher type
@@ -78,6 +82,77 @@ func (_c *Publisher_Publish_Call) RunAndReturn(run func([]byte, []string, ...fun
 	return _c
 }
 
+// PublishWithDeferredConfirmWithContext provides a mock function with given fields: ctx, data, routingKeys, optionFuncs
+func (_m *Publisher) PublishWithDeferredConfirmWithContext(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) ([]rmqproducer.Confirmation, error) {
+	_va := make([]interface{}, len(optionFuncs))
+	for _i := range optionFuncs {
+		_va[_i] = optionFuncs[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, data, routingKeys)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 []rmqproducer.Confirmation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, []string, ...func(*rabbitmq.PublishOptions)) ([]rmqproducer.Confirmation, error)); ok {
+		return rf(ctx, data, routingKeys, optionFuncs...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, []string, ...func(*rabbitmq.PublishOptions)) []rmqproducer.Confirmation); ok {
+		r0 = rf(ctx, data, routingKeys, optionFuncs...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]rmqproducer.Confirmation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []byte, []string, ...func(*rabbitmq.PublishOptions)) error); ok {
+		r1 = rf(ctx, data, routingKeys, optionFuncs...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Publisher_PublishWithDeferredConfirmWithContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishWithDeferredConfirmWithContext'
+type Publisher_PublishWithDeferredConfirmWithContext_Call struct {
+	*mock.Call
+}
+
+// PublishWithDeferredConfirmWithContext is a helper method to define mock.On call
+//   - ctx context.Context
+//   - data []byte
+//   - routingKeys []string
+//   - optionFuncs ...func(*rabbitmq.PublishOptions)
+func (_e *Publisher_Expecter) PublishWithDeferredConfirmWithContext(ctx interface{}, data interface{}, routingKeys interface{}, optionFuncs ...interface{}) *Publisher_PublishWithDeferredConfirmWithContext_Call {
+	return &Publisher_PublishWithDeferredConfirmWithContext_Call{Call: _e.mock.On("PublishWithDeferredConfirmWithContext",
+		append([]interface{}{ctx, data, routingKeys}, optionFuncs...)...)}
+}
+
+func (_c *Publisher_PublishWithDeferredConfirmWithContext_Call) Run(run func(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions))) *Publisher_PublishWithDeferredConfirmWithContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]func(*rabbitmq.PublishOptions), len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(func(*rabbitmq.PublishOptions))
+			}
+		}
+		run(args[0].(context.Context), args[1].([]byte), args[2].([]string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Publisher_PublishWithDeferredConfirmWithContext_Call) Return(_a0 []rmqproducer.Confirmation, _a1 error) *Publisher_PublishWithDeferredConfirmWithContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Publisher_PublishWithDeferredConfirmWithContext_Call) RunAndReturn(run func(context.Context, []byte, []string, ...func(*rabbitmq.PublishOptions)) ([]rmqproducer.Confirmation, error)) *Publisher_PublishWithDeferredConfirmWithContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 type mockConstructorTestingTNewPublisher interface {
 	mock.TestingT
 	Cleanup(func())
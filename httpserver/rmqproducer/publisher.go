@@ -1,28 +1,46 @@
 package rmqproducer
 
 import (
-	"errors"
-	"fmt"
-	"os"
+	"context"
 
+	"github.com/DennisPing/cs6650-twinder-a3/lib/routing"
 	"github.com/wagslane/go-rabbitmq"
 )
 
 //go:generate mockery --name=Publisher --filename=mock_publisher.go
 type Publisher interface {
 	Publish(data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) error
+	PublishWithDeferredConfirmWithContext(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) ([]Confirmation, error)
 }
 
-// Init a new RabbitMQ connection with the RabbitMQ host.
-func NewConnection() (*rabbitmq.Conn, error) {
-	host := os.Getenv("RABBITMQ_HOST")
+// The broker's ack or nack of one published message
+//
+//go:generate mockery --name=Confirmation --filename=mock_confirmation.go
+type Confirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+// A rabbitmq.Publisher whose confirms can be mocked
+type RabbitPublisher struct {
+	*rabbitmq.Publisher
+}
 
-	if host == "" {
-		return nil, errors.New("you forgot to set the RABBITMQ_HOST environment variable")
+// Publish a message and get one Confirmation per routing key. A confirmation is nil if the channel wasn't in confirm mode yet.
+func (p *RabbitPublisher) PublishWithDeferredConfirmWithContext(ctx context.Context, data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) ([]Confirmation, error) {
+	deferred, err := p.Publisher.PublishWithDeferredConfirmWithContext(ctx, data, routingKeys, optionFuncs...)
+	confirms := make([]Confirmation, len(deferred))
+	for i, confirm := range deferred {
+		if confirm != nil { // A nil pointer in the interface wouldn't compare equal to nil
+			confirms[i] = confirm
+		}
 	}
+	return confirms, err
+}
 
+// Init a new RabbitMQ connection with the amqp url of the broker.
+func NewConnection(url string) (*rabbitmq.Conn, error) {
 	conn, err := rabbitmq.NewConn(
-		fmt.Sprintf("amqp://%s:%s@%s:5672", "guest", "guest", host),
+		url,
 		rabbitmq.WithConnectionOptionsLogging,
 	)
 	if err != nil {
@@ -31,17 +49,22 @@ func NewConnection() (*rabbitmq.Conn, error) {
 	return conn, nil
 }
 
-// Create a new publisher that publishes to the "swipes" exchange via "fanout" method.
-func NewPublisher(conn *rabbitmq.Conn) (*rabbitmq.Publisher, error) {
+// Create a new publisher that publishes to the "swipes" exchange, declared as kind (fanout or topic).
+// With confirms, the broker acks or nacks every message and the channel is put into confirm mode shortly after.
+func NewPublisher(conn *rabbitmq.Conn, kind string, confirms bool) (*RabbitPublisher, error) {
 	publisher, err := rabbitmq.NewPublisher(
 		conn,
 		rabbitmq.WithPublisherOptionsLogging,
 		rabbitmq.WithPublisherOptionsExchangeDeclare,
-		rabbitmq.WithPublisherOptionsExchangeName("swipes"),
-		rabbitmq.WithPublisherOptionsExchangeKind("fanout"),
+		rabbitmq.WithPublisherOptionsExchangeName(routing.Exchange),
+		rabbitmq.WithPublisherOptionsExchangeKind(kind),
 	)
 	if err != nil {
 		return nil, err
 	}
-	return publisher, nil
+	if confirms {
+		// Registering a handler is what turns on confirm mode. Confirms are awaited per message instead.
+		publisher.NotifyPublish(func(rabbitmq.Confirmation) {})
+	}
+	return &RabbitPublisher{publisher}, nil
 }
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+)
+
+// Max userIds in one bulk lookup
+const maxBatchUsers = 500
+
+// POST /stats/batch
+func (s *Server) PostStatsBatch(w http.ResponseWriter, r *http.Request) {
+	userIds, records, ok := s.batchGetUsers(w, r)
+	if !ok {
+		return
+	}
+	resp := models.BatchUserStats{
+		Results: make([]models.UserStatsResult, 0, len(userIds)),
+	}
+	for _, userId := range userIds {
+		result := models.UserStatsResult{UserId: userId}
+		if record, found := records[userId]; found {
+			result.Found = true
+			result.Stats = &record.Stats
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	writeJsonResponse(w, r.Method, http.StatusOK, resp)
+}
+
+// POST /matches/batch
+func (s *Server) PostMatchesBatch(w http.ResponseWriter, r *http.Request) {
+	userIds, records, ok := s.batchGetUsers(w, r)
+	if !ok {
+		return
+	}
+	resp := models.BatchUserMatches{
+		Results: make([]models.UserMatchesResult, 0, len(userIds)),
+	}
+	for _, userId := range userIds {
+		result := models.UserMatchesResult{UserId: userId}
+		if record, found := records[userId]; found {
+			result.Found = true
+			result.Matches = &record.Matches
+			result.Matches.Count = len(record.Matches.MatchList)
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	writeJsonResponse(w, r.Method, http.StatusOK, resp)
+}
+
+// Decode a bulk lookup and fetch every user. Writes an error response and returns false if anything fails.
+func (s *Server) batchGetUsers(w http.ResponseWriter, r *http.Request) ([]int, map[int]models.UserRecord, bool) {
+	var br models.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&br); err != nil {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest, "bad request")
+		return nil, nil, false
+	}
+	if len(br.UserIds) == 0 || len(br.UserIds) > maxBatchUsers {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest,
+			fmt.Sprintf("userIds must have between 1 and %d entries", maxBatchUsers))
+		return nil, nil, false
+	}
+	records, err := s.store.BatchGetUsers(r.Context(), br.UserIds)
+	if err != nil {
+		writeErrorResponse(w, r.Method, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
+	}
+	return br.UserIds, records, true
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/store"
+	"github.com/go-chi/chi"
+)
+
+const (
+	defaultCommentsLimit = 20
+	maxCommentsLimit     = 100
+)
+
+// GET /comments/{userId}/?limit={int}&cursor={string}
+func (s *Server) GetComments(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "userId")
+	userIdInt, err := strconv.Atoi(userId)
+	if err != nil {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid userId: %s", userId))
+		return
+	}
+	limit := defaultCommentsLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxCommentsLimit {
+			writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid limit: %s", param))
+			return
+		}
+	}
+	cursor := r.URL.Query().Get("cursor")
+
+	comments, err := s.store.GetComments(r.Context(), userIdInt, limit, cursor)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid cursor: %s", cursor))
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, r.Method, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJsonResponse(w, r.Method, http.StatusOK, comments)
+}
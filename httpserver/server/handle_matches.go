@@ -1,14 +1,23 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/store"
 	"github.com/go-chi/chi"
 )
 
-// GET /matches/{userId}/
+const (
+	defaultMatchesLimit = 100
+	maxMatchesLimit     = 1000
+)
+
+// GET /matches/{userId}/?limit={int}&cursor={string}&order={asc|desc}
+// Without a limit or cursor every match is returned. Once either is given, pages hold up to
+// limit (default 100) matches and nextCursor points at the next page.
 func (s *Server) GetMatches(w http.ResponseWriter, r *http.Request) {
 	userId := chi.URLParam(r, "userId")
 	userIdInt, err := strconv.Atoi(userId)
@@ -16,6 +25,24 @@ func (s *Server) GetMatches(w http.ResponseWriter, r *http.Request) {
 		writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid userId: %s", userId))
 		return
 	}
+	cursor := r.URL.Query().Get("cursor")
+	limit := 0 // Everything, for clients that don't paginate
+	if cursor != "" {
+		limit = defaultMatchesLimit
+	}
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxMatchesLimit {
+			writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid limit: %s", param))
+			return
+		}
+	}
+	order := r.URL.Query().Get("order")
+	if order != "" && order != "asc" && order != "desc" {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid order: %s", order))
+		return
+	}
+
 	found, matches, err := s.store.GetMatches(r.Context(), userIdInt)
 	if err != nil {
 		writeErrorResponse(w, r.Method, http.StatusInternalServerError, err.Error())
@@ -25,5 +52,14 @@ func (s *Server) GetMatches(w http.ResponseWriter, r *http.Request) {
 		writeErrorResponse(w, r.Method, http.StatusNotFound, fmt.Sprintf("userId not found: %s", userId))
 		return
 	}
-	writeJsonResponse(w, r.Method, http.StatusOK, matches)
+	page, err := store.PageMatches(matches, limit, cursor, order == "desc")
+	if errors.Is(err, store.ErrInvalidCursor) {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid cursor: %s", cursor))
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, r.Method, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJsonResponse(w, r.Method, http.StatusOK, page)
 }
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/rmqproducer"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/rs/xid"
+)
+
+const (
+	maxStreamLine       = 64 * 1024 // Way more than a swipe with a 256 byte comment needs
+	maxStreamRejections = 1000      // Rejections listed in the summary, the count keeps going
+	streamConfirmWindow = 100       // Swipes published before waiting for their confirms
+)
+
+// A published line of a swipe stream waiting for its confirm
+type pendingLine struct {
+	line     int
+	body     []byte
+	confirms []rmqproducer.Confirmation
+}
+
+// POST /swipes/stream
+// Reads a newline delimited JSON body of swipes of any length. Lines are published in windows. With publish confirms on,
+// each window waits for its confirms before the next one is read, so a slow RabbitMQ slows down the client instead of
+// piling up swipes in memory. Without confirms the lines are published as fast as they are read.
+func (s *Server) PostSwipesStream(w http.ResponseWriter, r *http.Request) {
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 4096), maxStreamLine)
+
+	summary := models.StreamSwipeSummary{
+		Rejections: []models.LineError{},
+	}
+	// Keep the first rejected lines in order. Unconfirmed lines are rejected after later bad ones.
+	reject := func(line int, message string) {
+		summary.Rejected++
+		i := sort.Search(len(summary.Rejections), func(i int) bool { return summary.Rejections[i].Line > line })
+		if i == maxStreamRejections {
+			return
+		}
+		summary.Rejections = append(summary.Rejections, models.LineError{})
+		copy(summary.Rejections[i+1:], summary.Rejections[i:])
+		summary.Rejections[i] = models.LineError{Line: line, Message: message}
+		if len(summary.Rejections) > maxStreamRejections {
+			summary.Rejections = summary.Rejections[:maxStreamRejections]
+		}
+	}
+
+	accept := func(line int, err error) {
+		if err != nil {
+			zlog.Error().Err(err).Int("line", line).Msg("failed to publish swipe in stream")
+			reject(line, "failed to publish message")
+			return
+		}
+		summary.Accepted++
+		s.metrics.IncrementThroughput()
+	}
+
+	// Wait for the window's confirms together, like a batch
+	window := make([]pendingLine, 0, streamConfirmWindow)
+	start := time.Now()
+	flush := func() {
+		confirmCtx, cancel := s.confirmContext(r.Context())
+		defer cancel()
+		for _, p := range window {
+			if err := s.waitConfirms(confirmCtx, start, p.confirms); err != nil {
+				accept(p.line, s.spoolSwipe(p.body, err))
+				continue
+			}
+			accept(p.line, nil)
+		}
+		window = window[:0]
+		start = time.Now()
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue // Allow blank lines, like the one at the end of most files
+		}
+		summary.Lines++
+
+		var sr models.SwipeRequest
+		if err := json.Unmarshal(scanner.Bytes(), &sr); err != nil {
+			reject(line, "bad request")
+			continue
+		}
+		if err := validateSwipe(sr, sr.Direction); err != nil {
+			reject(line, err.Error())
+			continue
+		}
+		sr.SwipeId = xid.New().String()
+		body, _ := json.Marshal(sr)
+		if s.spooling() {
+			accept(line, s.spoolSwipe(body, nil))
+			continue
+		}
+		confirms, err := s.publish(r.Context(), body)
+		if err != nil {
+			accept(line, s.spoolSwipe(body, err))
+			continue
+		}
+		window = append(window, pendingLine{line: line, body: body, confirms: confirms})
+		if len(window) == streamConfirmWindow {
+			flush()
+		}
+	}
+	flush()
+
+	// Everything before the bad line was already published, so still report it
+	if err := scanner.Err(); err != nil {
+		summary.Error = fmt.Sprintf("line %d: %s", line+1, err)
+		writeJsonResponse(w, r.Method, http.StatusBadRequest, summary)
+		return
+	}
+	writeJsonResponse(w, r.Method, http.StatusOK, summary)
+}
@@ -2,12 +2,16 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/rmqproducer"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
 	"github.com/go-chi/chi"
+	"github.com/rs/xid"
 )
 
 // POST /swipe/{leftorright}/
@@ -21,29 +25,19 @@ func (s *Server) PostSwipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := strconv.Atoi(sr.Swiper); err != nil {
-		writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid swiper: %s", sr.Swiper))
-		return
-	}
-	if _, err := strconv.Atoi(sr.Swipee); err != nil {
-		writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("invalid swipee: %s", sr.Swipee))
-		return
-	}
-	if len(sr.Comment) > 256 {
-		writeErrorResponse(w, r.Method, http.StatusBadRequest, "comment too long")
-		return
-	}
-	if leftorright != "left" && leftorright != "right" {
-		writeErrorResponse(w, r.Method, http.StatusBadRequest, fmt.Sprintf("not left or right: %s", leftorright))
+	if err = validateSwipe(sr, leftorright); err != nil {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Append the direction to the request body
+	// Append the direction and a unique swipe id to the request body
 	sr.Direction = leftorright
+	sr.SwipeId = xid.New().String()
 
 	// Publish the message
-	if err = s.PublishToRmq(sr); err != nil {
-		writeErrorResponse(w, r.Method, http.StatusInternalServerError, "failed to publish message")
+	if err = s.PublishToRmq(r.Context(), sr); err != nil {
+		zlog.Error().Err(err).Msg("failed to publish swipe")
+		writeErrorResponse(w, r.Method, publishErrorStatus(err), "failed to publish message")
 		return
 	}
 
@@ -58,3 +52,92 @@ func (s *Server) PostSwipe(w http.ResponseWriter, r *http.Request) {
 		s.metrics.IncrementThroughput()
 	}
 }
+
+// Check that a swipe has numeric user ids, a short enough comment, and a valid direction
+func validateSwipe(sr models.SwipeRequest, leftorright string) error {
+	if _, err := strconv.Atoi(sr.Swiper); err != nil {
+		return fmt.Errorf("invalid swiper: %s", sr.Swiper)
+	}
+	if _, err := strconv.Atoi(sr.Swipee); err != nil {
+		return fmt.Errorf("invalid swipee: %s", sr.Swipee)
+	}
+	if len(sr.Comment) > 256 {
+		return errors.New("comment too long")
+	}
+	if leftorright != "left" && leftorright != "right" {
+		return fmt.Errorf("not left or right: %s", leftorright)
+	}
+	return nil
+}
+
+// POST /swipes/batch
+// Every swipe is validated and published on its own, and then the whole batch waits for its confirms within one
+// ConfirmTimeout. Responds 201 if all swipes were accepted, otherwise 207 with the status of each swipe.
+func (s *Server) PostSwipesBatch(w http.ResponseWriter, r *http.Request) {
+	var batch []models.SwipeRequest
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest, "bad request")
+		return
+	}
+	if len(batch) == 0 || len(batch) > s.MaxSwipeBatch {
+		writeErrorResponse(w, r.Method, http.StatusBadRequest,
+			fmt.Sprintf("batch must have between 1 and %d swipes", s.MaxSwipeBatch))
+		return
+	}
+
+	resp := models.BatchSwipeResponse{
+		Results: make([]models.SwipeResult, len(batch)),
+	}
+	accept := func(i int, err error) {
+		if err != nil {
+			zlog.Error().Err(err).Msg("failed to publish swipe in batch")
+			resp.Results[i] = models.SwipeResult{Status: publishErrorStatus(err), Message: "failed to publish message"}
+			return
+		}
+		resp.Accepted++
+		s.metrics.IncrementThroughput()
+	}
+
+	// Publish everything first and then wait for the confirms, so they arrive in parallel
+	start := time.Now()
+	bodies := make([][]byte, len(batch))
+	confirms := make([][]rmqproducer.Confirmation, len(batch))
+	published := make([]bool, len(batch))
+	for i, sr := range batch {
+		if err := validateSwipe(sr, sr.Direction); err != nil {
+			resp.Results[i] = models.SwipeResult{Status: http.StatusBadRequest, Message: err.Error()}
+			continue
+		}
+		sr.SwipeId = xid.New().String()
+		resp.Results[i] = models.SwipeResult{Status: http.StatusCreated, SwipeId: sr.SwipeId}
+		bodies[i], _ = json.Marshal(sr)
+		if s.spooling() {
+			accept(i, s.spoolSwipe(bodies[i], nil))
+			continue
+		}
+		confirm, err := s.publish(r.Context(), bodies[i])
+		if err != nil {
+			accept(i, s.spoolSwipe(bodies[i], err))
+			continue
+		}
+		confirms[i], published[i] = confirm, true
+	}
+	confirmCtx, cancel := s.confirmContext(r.Context())
+	defer cancel()
+	for i := range batch {
+		if !published[i] {
+			continue
+		}
+		if err := s.waitConfirms(confirmCtx, start, confirms[i]); err != nil {
+			accept(i, s.spoolSwipe(bodies[i], err))
+			continue
+		}
+		accept(i, nil)
+	}
+
+	if resp.Accepted == len(batch) {
+		writeJsonResponse(w, r.Method, http.StatusCreated, resp)
+	} else {
+		writeJsonResponse(w, r.Method, http.StatusMultiStatus, resp)
+	}
+}
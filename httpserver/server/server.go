@@ -3,6 +3,8 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,28 +12,49 @@ import (
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/metrics"
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/middleware"
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/rmqproducer"
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/spool"
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/store"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+	libmetrics "github.com/DennisPing/cs6650-twinder-a3/lib/metrics"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/routing"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
 	"github.com/go-chi/chi"
 	"github.com/wagslane/go-rabbitmq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var zlog = logger.GetLogger()
 
+// The original shard ranges, used unless a Router is set
+var defaultRouter, _ = shard.NewRangeRouter(shard.DefaultRanges)
+
+// Returned when the broker nacks a swipe or doesn't confirm it in time
+var ErrNotConfirmed = errors.New("publish not confirmed by broker")
+
 type Server struct {
 	http.Server
-	metrics metrics.Metrics       // interface
-	pub     rmqproducer.Publisher // interface
-	store   *store.DatabaseClient
-	ticker  *time.Ticker
-	cancel  context.CancelFunc
+	ConfirmTimeout  time.Duration         // How long to wait for publisher confirms, 0 means don't wait
+	Spool           *spool.Spool          // Where swipes go while RabbitMQ is down, nil means they fail
+	Router          shard.Router          // Picks the shard in routing keys, must match the consumers' shard config
+	MaxSwipeBatch   int                   // Most swipes in one POST /swipes/batch
+	MetricsInterval time.Duration         // How often metrics are sent
+	metrics         metrics.Metrics       // interface
+	pub             rmqproducer.Publisher // interface
+	store           store.Store           // interface
+	ticker          *time.Ticker
+	cancel          context.CancelFunc
 }
 
 // Create a new server which has an HTTP server, Metrics client, RabbitMQ publisher, and Database client
-func NewServer(addr string, metrics metrics.Metrics, publisher rmqproducer.Publisher, dbClient *store.DatabaseClient) *Server {
+func NewServer(addr string, metricsClient metrics.Metrics, publisher rmqproducer.Publisher, dbClient store.Store) *Server {
 	chiRouter := chi.NewRouter()
-	chiRouter.Use(middleware.LoggingMiddleware)
+	chiRouter.Use(middleware.LoggingMiddleware(metricsClient))
+	if exporter, ok := metricsClient.(libmetrics.Exporter); ok { // Scraped instead of sent
+		chiRouter.Method(http.MethodGet, "/metrics", exporter.Handler())
+	}
 
 	// Build the server
 	s := &Server{
@@ -39,20 +62,28 @@ func NewServer(addr string, metrics metrics.Metrics, publisher rmqproducer.Publi
 			Addr:    addr,
 			Handler: chiRouter,
 		},
-		metrics: metrics,
-		pub:     publisher,
-		store:   dbClient,
+		metrics:         metricsClient,
+		pub:             publisher,
+		store:           dbClient,
+		Router:          defaultRouter,
+		MaxSwipeBatch:   models.DefaultMaxSwipeBatch,
+		MetricsInterval: 5 * time.Second,
 	}
 	chiRouter.Get("/health", s.GetHealth)
 	chiRouter.Post("/swipe/{leftorright}/", s.PostSwipe)
+	chiRouter.Post("/swipes/batch", s.PostSwipesBatch)
+	chiRouter.Post("/swipes/stream", s.PostSwipesStream)
 	chiRouter.Get("/matches/{userId}/", s.GetMatches)
 	chiRouter.Get("/stats/{userId}/", s.GetStats)
+	chiRouter.Get("/comments/{userId}/", s.GetComments)
+	chiRouter.Post("/stats/batch", s.PostStatsBatch)
+	chiRouter.Post("/matches/batch", s.PostMatchesBatch)
 	return s
 }
 
 // Start the server and start metrics on a new goroutine
 func (s *Server) Start() error {
-	s.ticker = time.NewTicker(5 * time.Second)
+	s.ticker = time.NewTicker(s.MetricsInterval)
 	ctx, cancel := context.WithCancel(context.Background())
 	s.cancel = cancel
 	go func() { // Metrics goroutine
@@ -61,13 +92,21 @@ func (s *Server) Start() error {
 			case <-ctx.Done(): // Quit
 				return
 			case <-s.ticker.C: // Keep on ticking
+				if s.Spool != nil {
+					s.metrics.SetSpoolDepth(s.Spool.Depth())
+				}
 				err := s.metrics.SendMetrics()
 				if err != nil {
-					zlog.Error().Err(err).Msg("unable to send metrics to Axiom")
+					zlog.Error().Err(err).Msg("unable to send metrics")
 				}
 			}
 		}
 	}()
+	if s.Spool != nil {
+		go s.Spool.Run(ctx, time.Second, func(body []byte) error {
+			return s.sendToRmq(ctx, body)
+		})
+	}
 	return s.ListenAndServe()
 }
 
@@ -84,20 +123,142 @@ func (s *Server) Stop() {
 	}
 }
 
-// Publish a message out to the RabbitMQ exchange
-func (s *Server) PublishToRmq(payload interface{}) error {
+// Publish a message out to the RabbitMQ exchange. With a ConfirmTimeout, blocks until the broker confirms it.
+// With a Spool, messages that can't be published are spooled to disk and replayed later.
+func (s *Server) PublishToRmq(ctx context.Context, payload interface{}) (err error) {
 	zlog.Debug().Interface("payload", payload).Msg("publish")
+	ctx, span := tracing.Start(ctx, "PublishToRmq", trace.WithSpanKind(trace.SpanKindProducer))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
 
-	respBytes, err := json.Marshal(payload)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if s.spooling() {
+		span.AddEvent("spooled") // Replayed without the trace context
+		return s.spoolSwipe(body, nil)
+	}
+	if err = s.sendToRmq(ctx, body); err != nil {
+		span.AddEvent("spooled", trace.WithAttributes(attribute.String("cause", err.Error())))
+		return s.spoolSwipe(body, err)
+	}
+	return nil
+}
+
+// Internal method that publishes a message and waits for the broker to confirm it
+func (s *Server) sendToRmq(ctx context.Context, body []byte) error {
+	confirms, err := s.publish(ctx, body)
 	if err != nil {
 		return err
 	}
-	return s.pub.Publish(
-		[]byte(respBytes),
-		[]string{""},
+	return s.awaitConfirms(ctx, confirms)
+}
+
+// Internal method that publishes a swipe without waiting for the broker to confirm it
+func (s *Server) publish(ctx context.Context, body []byte) ([]rmqproducer.Confirmation, error) {
+	options := []func(*rabbitmq.PublishOptions){
 		rabbitmq.WithPublishOptionsContentType("application/json"),
-		rabbitmq.WithPublishOptionsExchange("swipes"),
-	)
+		rabbitmq.WithPublishOptionsExchange(routing.Exchange),
+	}
+	headers := rabbitmq.Table{}
+	tracing.Inject(ctx, headers) // The consumer continues the trace
+	if len(headers) > 0 {
+		options = append(options, rabbitmq.WithPublishOptionsHeaders(headers))
+	}
+	keys := []string{s.routingKey(body)}
+	start := time.Now()
+	var confirms []rmqproducer.Confirmation
+	var err error
+	if s.ConfirmTimeout == 0 {
+		err = s.pub.Publish(body, keys, options...)
+	} else {
+		confirms, err = s.pub.PublishWithDeferredConfirmWithContext(ctx, body, keys, options...)
+	}
+	s.metrics.RecordPublish(time.Since(start), err)
+	return confirms, err
+}
+
+// Internal method that gets the routing key of a published swipe
+func (s *Server) routingKey(body []byte) string {
+	var sr models.SwipeRequest
+	if err := json.Unmarshal(body, &sr); err != nil {
+		return "" // Not a swipe
+	}
+	return routing.Key(s.Router, sr)
+}
+
+// Internal method that reports whether older messages are still waiting in the spool.
+// New messages have to be spooled behind them to keep their order.
+func (s *Server) spooling() bool {
+	return s.Spool != nil && s.Spool.Depth() > 0
+}
+
+// Internal method that spools a message after publishing failed with cause. Returns cause, or the spool error
+// if there was no cause, when the message couldn't be spooled.
+func (s *Server) spoolSwipe(body []byte, cause error) error {
+	if s.Spool == nil {
+		return cause
+	}
+	if err := s.Spool.Append(body); err != nil {
+		zlog.Error().Err(err).AnErr("cause", cause).Msg("unable to spool message")
+		if cause == nil {
+			return fmt.Errorf("failed to spool message: %w", err)
+		}
+		return cause
+	}
+	if cause != nil {
+		zlog.Warn().Err(cause).Msg("unable to publish message, spooled it for later")
+	}
+	return nil
+}
+
+// Internal method that waits up to ConfirmTimeout for the broker to ack every published message
+func (s *Server) awaitConfirms(ctx context.Context, confirms []rmqproducer.Confirmation) error {
+	start := time.Now()
+	ctx, cancel := s.confirmContext(ctx)
+	defer cancel()
+	return s.waitConfirms(ctx, start, confirms)
+}
+
+// Internal method that bounds a wait for confirms by ConfirmTimeout. Many publishes can share it to wait in parallel.
+func (s *Server) confirmContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.ConfirmTimeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.ConfirmTimeout)
+}
+
+// Internal method that waits until ctx is done for the broker to ack every message published since start.
+// A nil confirmation means the channel wasn't in confirm mode yet, right after a (re)connect, so nobody will ack it.
+func (s *Server) waitConfirms(ctx context.Context, start time.Time, confirms []rmqproducer.Confirmation) error {
+	if s.ConfirmTimeout == 0 {
+		return nil
+	}
+	for _, confirm := range confirms {
+		if confirm == nil {
+			return fmt.Errorf("%w: channel not in confirm mode", ErrNotConfirmed)
+		}
+		acked, err := confirm.WaitContext(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNotConfirmed, err)
+		}
+		if !acked {
+			return fmt.Errorf("%w: nacked", ErrNotConfirmed)
+		}
+	}
+	s.metrics.RecordConfirmLatency(time.Since(start))
+	return nil
+}
+
+// The HTTP status for a failed publish. Unconfirmed swipes may be retried by the client.
+func publishErrorStatus(err error) int {
+	if errors.Is(err, ErrNotConfirmed) || errors.Is(err, spool.ErrFull) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
 }
 
 // Send a simple HTTP response with no payload
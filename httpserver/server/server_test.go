@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	mockMetrics "github.com/DennisPing/cs6650-twinder-a3/httpserver/metrics/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/rmqproducer"
 	mockPublisher "github.com/DennisPing/cs6650-twinder-a3/httpserver/rmqproducer/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/spool"
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/store"
 	mockDynamo "github.com/DennisPing/cs6650-twinder-a3/httpserver/store/mocks"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
@@ -19,17 +23,25 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/wagslane/go-rabbitmq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 func TestPostSwipe(t *testing.T) {
 	// Mock the internal Metrics
 	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 	mockMetrics.EXPECT().IncrementThroughput().Return()
+	mockMetrics.EXPECT().RecordPublish(mock.Anything, nil).Return()
 
 	// Mock the internal Publisher
 	mockPublisher := mockPublisher.NewPublisher(t)
 	mockPublisher.EXPECT().Publish(
-		mock.AnythingOfType("[]uint8"),
+		mock.MatchedBy(func(body []byte) bool { // Every published swipe gets a swipe id
+			var sr models.SwipeRequest
+			return json.Unmarshal(body, &sr) == nil && sr.SwipeId != ""
+		}),
 		mock.AnythingOfType("[]string"),
 		mock.AnythingOfType("func(*rabbitmq.PublishOptions)"),
 		mock.AnythingOfType("func(*rabbitmq.PublishOptions)")).
@@ -97,9 +109,45 @@ func TestPostSwipe(t *testing.T) {
 	}
 }
 
+// The trace context of the request is published in the AMQP headers, so the consumer can continue the trace
+func TestPostSwipeTraceContext(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagator) })
+
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockMetrics.EXPECT().IncrementThroughput().Return()
+	mockMetrics.EXPECT().RecordPublish(mock.Anything, nil).Return()
+
+	var published rabbitmq.PublishOptions
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockPublisher.EXPECT().Publish(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(data []byte, routingKeys []string, optionFuncs ...func(*rabbitmq.PublishOptions)) {
+			for _, optionFunc := range optionFuncs {
+				optionFunc(&published)
+			}
+		}).
+		Return(nil).Once()
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, &store.DatabaseClient{})
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	bodyBytes, _ := json.Marshal(models.SwipeRequest{Swiper: "1234", Swipee: "5678", Comment: "asdf"})
+	req, _ := http.NewRequest("POST", "/swipe/left/", bytes.NewReader(bodyBytes))
+	req.Header.Set("traceparent", traceparent)
+	rr := httptest.NewRecorder()
+	s.Handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	header, _ := published.Headers["traceparent"].(string)
+	assert.Contains(t, header, "4bf92f3577b34da6a3ce929d0e0e4736") // Same trace as the request
+}
+
 func TestPostSwipeError(t *testing.T) {
 	// Mock internal dependencies
 	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
 	mockPublisher := mockPublisher.NewPublisher(t)
 	mockDynamoClient := mockDynamo.NewDynamoClienter(t)
 
@@ -183,8 +231,297 @@ func TestPostSwipeError(t *testing.T) {
 	}
 }
 
+// A confirm the broker already acked
+func ackedConfirm(t *testing.T) rmqproducer.Confirmation {
+	confirm := mockPublisher.NewConfirmation(t)
+	confirm.EXPECT().WaitContext(mock.Anything).Return(true, nil)
+	return confirm
+}
+
+// A confirm the broker never answers
+func pendingConfirm(t *testing.T) rmqproducer.Confirmation {
+	confirm := mockPublisher.NewConfirmation(t)
+	confirm.EXPECT().WaitContext(mock.Anything).RunAndReturn(func(ctx context.Context) (bool, error) {
+		<-ctx.Done()
+		return false, ctx.Err()
+	})
+	return confirm
+}
+
+func TestPostSwipeConfirms(t *testing.T) {
+	tests := []struct {
+		name           string
+		confirms       []rmqproducer.Confirmation
+		publishErr     error
+		expectedStatus int
+	}{
+		{
+			name:           "confirmed",
+			confirms:       []rmqproducer.Confirmation{ackedConfirm(t)},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "not in confirm mode yet", // Published right after a reconnect
+			confirms:       []rmqproducer.Confirmation{nil},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "confirm timed out",
+			confirms:       []rmqproducer.Confirmation{pendingConfirm(t)},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "publish failed",
+			publishErr:     errors.New("channel closed"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockMetrics := mockMetrics.NewMetrics(t)
+			mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+			mockPublisher := mockPublisher.NewPublisher(t)
+			mockPublisher.EXPECT().PublishWithDeferredConfirmWithContext(
+				mock.Anything, mock.Anything, []string{"swipe.right.SwipeData2"}, mock.Anything, mock.Anything).
+				Return(tc.confirms, tc.publishErr)
+			mockMetrics.EXPECT().RecordPublish(mock.Anything, tc.publishErr).Return()
+			if tc.expectedStatus == http.StatusCreated {
+				mockMetrics.EXPECT().RecordConfirmLatency(mock.Anything).Return()
+				mockMetrics.EXPECT().IncrementThroughput().Return()
+			}
+
+			s := NewServer(":8080", mockMetrics, mockPublisher, mockDynamo.NewStore(t))
+			s.ConfirmTimeout = 10 * time.Millisecond
+
+			body := `{"swiper":"1234","swipee":"5678","comment":"hi"}`
+			req, _ := http.NewRequest("POST", "/swipe/right/", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			s.Handler.ServeHTTP(rr, req)
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+// Swipes are spooled while RabbitMQ is down and replayed once it's back
+func TestPostSwipeSpools(t *testing.T) {
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockMetrics.EXPECT().IncrementThroughput().Return().Twice()
+	mockMetrics.EXPECT().RecordPublish(mock.Anything, mock.Anything).Return().Times(3) // Failed once, then replayed twice
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockPublisher.EXPECT().Publish(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("connection closed")).Once()
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, mockDynamo.NewStore(t))
+	swipeSpool, err := spool.Open(t.TempDir(), 1<<20)
+	assert.NoError(t, err)
+	defer swipeSpool.Close()
+	s.Spool = swipeSpool
+
+	for _, swipee := range []string{"5678", "5679"} { // The second swipe queues behind the first
+		body := `{"swiper":"1234","swipee":"` + swipee + `"}`
+		req, _ := http.NewRequest("POST", "/swipe/right/", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		s.Handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	}
+	assert.Equal(t, 2, swipeSpool.Depth())
+
+	var swipees []string
+	mockPublisher.EXPECT().Publish(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(data []byte, _ []string, _ ...func(*rabbitmq.PublishOptions)) {
+			var sr models.SwipeRequest
+			assert.NoError(t, json.Unmarshal(data, &sr))
+			swipees = append(swipees, sr.Swipee)
+		}).
+		Return(nil).Twice()
+	replayed, err := swipeSpool.Replay(func(body []byte) error {
+		return s.sendToRmq(context.Background(), body)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, []string{"5678", "5679"}, swipees)
+}
+
+func TestPostSwipesBatch(t *testing.T) {
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockMetrics.EXPECT().IncrementThroughput().Return().Twice()
+	mockMetrics.EXPECT().RecordPublish(mock.Anything, mock.Anything).Return()
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockPublisher.EXPECT().Publish(
+		mock.MatchedBy(func(body []byte) bool {
+			var sr models.SwipeRequest
+			return json.Unmarshal(body, &sr) == nil && sr.SwipeId != "" && sr.Swipee != "9999"
+		}),
+		mock.Anything, mock.Anything, mock.Anything).
+		Return(nil).Twice()
+	mockPublisher.EXPECT().Publish(
+		mock.MatchedBy(func(body []byte) bool {
+			var sr models.SwipeRequest
+			return json.Unmarshal(body, &sr) == nil && sr.Swipee == "9999"
+		}),
+		mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("rabbitmq died")).Once()
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, mockDynamo.NewStore(t))
+
+	bodyBytes, _ := json.Marshal([]models.SwipeRequest{
+		{Swiper: "1234", Swipee: "5678", Direction: "left"},
+		{Swiper: "1234", Swipee: "abc", Direction: "right"},
+		{Swiper: "1234", Swipee: "5679", Direction: "right"},
+		{Swiper: "1234", Swipee: "5680", Direction: "middle"},
+		{Swiper: "1234", Swipee: "9999", Direction: "right"},
+	})
+	req, _ := http.NewRequest("POST", "/swipes/batch", bytes.NewReader(bodyBytes))
+	rr := httptest.NewRecorder()
+	s.Handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusMultiStatus, rr.Code)
+	var resp models.BatchSwipeResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Accepted)
+	statuses := make([]int, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []int{201, 400, 201, 400, 500}, statuses)
+	assert.NotEmpty(t, resp.Results[0].SwipeId)
+	assert.Equal(t, "invalid swipee: abc", resp.Results[1].Message)
+	assert.Equal(t, "not left or right: middle", resp.Results[3].Message)
+
+	s.MaxSwipeBatch = 1 // Too small for the next batch
+	for _, body := range []string{`[]`, `{"swiper":"1234"}`, string(bodyBytes)} {
+		req, _ := http.NewRequest("POST", "/swipes/batch", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		s.Handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestPostSwipesStream(t *testing.T) {
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockMetrics.EXPECT().IncrementThroughput().Return().Twice()
+	mockMetrics.EXPECT().RecordPublish(mock.Anything, nil).Return()
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockPublisher.EXPECT().Publish(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Twice()
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, mockDynamo.NewStore(t))
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expected       models.StreamSwipeSummary
+	}{
+		{
+			name: "mixed lines",
+			body: `{"swiper":"1234","swipee":"5678","direction":"left"}` + "\n" +
+				`{"swiper":"1234","swipee":"abc","direction":"left"}` + "\n" +
+				"\n" +
+				`not json` + "\n" +
+				`{"swiper":"1234","swipee":"5679","direction":"right"}` + "\n",
+			expectedStatus: http.StatusOK,
+			expected: models.StreamSwipeSummary{
+				Lines:    4,
+				Accepted: 2,
+				Rejected: 2,
+				Rejections: []models.LineError{
+					{Line: 2, Message: "invalid swipee: abc"},
+					{Line: 4, Message: "bad request"},
+				},
+			},
+		},
+		{
+			name:           "line too long",
+			body:           strings.Repeat("a", maxStreamLine+1),
+			expectedStatus: http.StatusBadRequest,
+			expected: models.StreamSwipeSummary{
+				Rejections: []models.LineError{},
+				Error:      "line 1: bufio.Scanner: token too long",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/swipes/stream", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			s.Handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			var summary models.StreamSwipeSummary
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &summary))
+			assert.Equal(t, tc.expected, summary)
+		})
+	}
+}
+
+// A window of streamed swipes is published before any of its confirms are awaited
+func TestPostSwipesStreamConfirms(t *testing.T) {
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockMetrics.EXPECT().RecordPublish(mock.Anything, nil).Return().Twice()
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockPublisher.EXPECT().PublishWithDeferredConfirmWithContext(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]rmqproducer.Confirmation{pendingConfirm(t)}, nil).Twice()
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, mockDynamo.NewStore(t))
+	s.ConfirmTimeout = 50 * time.Millisecond
+
+	body := `{"swiper":"1234","swipee":"5678","direction":"left"}` + "\n" +
+		`not json` + "\n" +
+		`{"swiper":"1234","swipee":"5679","direction":"right"}` + "\n"
+	req, _ := http.NewRequest("POST", "/swipes/stream", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	start := time.Now()
+	s.Handler.ServeHTTP(rr, req)
+
+	assert.Less(t, time.Since(start), 2*s.ConfirmTimeout) // One shared timeout, not one per line
+	var summary models.StreamSwipeSummary
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &summary))
+	assert.Equal(t, models.StreamSwipeSummary{
+		Lines:    3,
+		Rejected: 3,
+		Rejections: []models.LineError{
+			{Line: 1, Message: "failed to publish message"},
+			{Line: 2, Message: "bad request"},
+			{Line: 3, Message: "failed to publish message"},
+		},
+	}, summary)
+}
+
+// The summary lists the first rejected lines even when an unconfirmed line is rejected after the list is full
+func TestPostSwipesStreamRejectionsCapped(t *testing.T) {
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockMetrics.EXPECT().RecordPublish(mock.Anything, nil).Return().Once()
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockPublisher.EXPECT().PublishWithDeferredConfirmWithContext(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]rmqproducer.Confirmation{pendingConfirm(t)}, nil).Once()
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, mockDynamo.NewStore(t))
+	s.ConfirmTimeout = 10 * time.Millisecond
+
+	body := `{"swiper":"1234","swipee":"5678","direction":"left"}` + "\n" +
+		strings.Repeat("not json\n", maxStreamRejections+100)
+	req, _ := http.NewRequest("POST", "/swipes/stream", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	s.Handler.ServeHTTP(rr, req)
+
+	var summary models.StreamSwipeSummary
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &summary))
+	assert.Equal(t, maxStreamRejections+101, summary.Rejected)
+	assert.Len(t, summary.Rejections, maxStreamRejections)
+	assert.Equal(t, models.LineError{Line: 1, Message: "failed to publish message"}, summary.Rejections[0])
+	assert.Equal(t, maxStreamRejections, summary.Rejections[maxStreamRejections-1].Line)
+}
+
 func TestGetUserStatsHandler(t *testing.T) {
 	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest("/stats/{userId}/", "GET", 200, mock.Anything).Return().Once()
 	mockPublisher := mockPublisher.NewPublisher(t)
 
 	wantItem := &models.DynamoUserStats{
@@ -214,6 +551,131 @@ func TestGetUserStatsHandler(t *testing.T) {
 	assert.Equal(t, 22, stat.NumDislikes)
 }
 
+func TestGetMatchesHandler(t *testing.T) {
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockStore := mockDynamo.NewStore(t)
+	mockStore.EXPECT().GetMatches(mock.Anything, 1234).
+		Return(true, models.UserMatches{MatchList: []int{1003, 1001, 1002}}, nil)
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, mockStore)
+
+	// Follow the cursor through every page
+	get := func(url string) (int, models.UserMatches) {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		s.Handler.ServeHTTP(rr, req)
+		var matches models.UserMatches
+		_ = json.Unmarshal(rr.Body.Bytes(), &matches)
+		return rr.Code, matches
+	}
+	code, page := get("/matches/1234/?limit=2&order=desc")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{1003, 1002}, page.MatchList)
+	assert.Equal(t, 3, page.Count)
+
+	code, page = get("/matches/1234/?limit=2&order=desc&cursor=" + page.NextCursor)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{1001}, page.MatchList)
+	assert.Empty(t, page.NextCursor)
+
+	code, page = get("/matches/1234/") // no limit or cursor gets every match
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{1001, 1002, 1003}, page.MatchList)
+	assert.Empty(t, page.NextCursor)
+
+	code, _ = get("/matches/1234/?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/matches/1234/?order=sideways")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("/matches/1234/?cursor=bad")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestBatchHandlers(t *testing.T) {
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockStore := mockDynamo.NewStore(t)
+	mockStore.EXPECT().BatchGetUsers(mock.Anything, []int{1234, 5678}).Return(map[int]models.UserRecord{
+		1234: {Stats: models.UserStats{NumLikes: 3}, Matches: models.UserMatches{MatchList: []int{7, 8}}},
+	}, nil).Twice()
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, mockStore)
+	post := func(url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		s.Handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post("/stats/batch", `{"userIds":[1234,5678]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var stats models.BatchUserStats
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &stats))
+	assert.Equal(t, []models.UserStatsResult{
+		{UserId: 1234, Found: true, Stats: &models.UserStats{NumLikes: 3}},
+		{UserId: 5678, Found: false},
+	}, stats.Results)
+
+	rr = post("/matches/batch", `{"userIds":[1234,5678]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var matches models.BatchUserMatches
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &matches))
+	assert.Equal(t, []models.UserMatchesResult{
+		{UserId: 1234, Found: true, Matches: &models.UserMatches{MatchList: []int{7, 8}, Count: 2}},
+		{UserId: 5678, Found: false},
+	}, matches.Results)
+
+	assert.Equal(t, http.StatusBadRequest, post("/stats/batch", `{"userIds":[]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post("/matches/batch", `not json`).Code)
+}
+
+func TestGetCommentsHandler(t *testing.T) {
+	mockMetrics := mockMetrics.NewMetrics(t)
+	mockMetrics.EXPECT().RecordRequest(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockPublisher := mockPublisher.NewPublisher(t)
+	mockStore := mockDynamo.NewStore(t)
+
+	wantComments := models.UserComments{
+		Comments:   []models.Comment{{From: 1234, Comment: "hello", Direction: "right"}},
+		NextCursor: "abc",
+	}
+	mockStore.EXPECT().GetComments(mock.Anything, 5678, 1, "").Return(wantComments, nil)
+	mockStore.EXPECT().GetComments(mock.Anything, 5678, 20, "bad").Return(models.UserComments{}, store.ErrInvalidCursor)
+
+	s := NewServer(":8080", mockMetrics, mockPublisher, mockStore)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{name: "first page", url: "/comments/5678/?limit=1", expectedStatus: http.StatusOK},
+		{name: "bad cursor", url: "/comments/5678/?cursor=bad", expectedStatus: http.StatusBadRequest},
+		{name: "bad limit", url: "/comments/5678/?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "bad userId", url: "/comments/abc/", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tc.url, nil)
+			rr := httptest.NewRecorder()
+			s.Handler.ServeHTTP(rr, req)
+			resp := rr.Result()
+
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			if tc.expectedStatus == http.StatusOK {
+				var comments models.UserComments
+				body, _ := io.ReadAll(resp.Body)
+				_ = json.Unmarshal(body, &comments)
+				assert.Equal(t, wantComments, comments)
+			}
+		})
+	}
+}
+
 // Convert a message to an error json
 func errorJson(message string) string {
 	encoded, _ := json.Marshal(
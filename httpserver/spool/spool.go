@@ -0,0 +1,271 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+)
+
+var zlog = logger.GetLogger()
+
+// Returned when appending would grow the spool past its max size
+var ErrFull = errors.New("spool is full")
+
+// Replayed messages between offset saves. A crash replays up to this many again, which the consumer skips by swipe id.
+const offsetSaveInterval = 100
+
+// An append-only log of messages that couldn't be published, one per line. Messages are replayed in order
+// and the replayed position is saved in a separate offset file, so a restart doesn't replay them all again.
+// The log is truncated once everything has been replayed.
+type Spool struct {
+	mu         sync.Mutex
+	file       *os.File
+	offsetPath string
+	maxBytes   int64
+	size       int64 // Bytes in the log
+	offset     int64 // Bytes already replayed
+	depth      int   // Messages not replayed yet
+}
+
+// Open the spool in dir, creating it if it doesn't exist
+func Open(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool dir: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, "spool.log"), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool: %w", err)
+	}
+	s := &Spool{
+		file:       file,
+		offsetPath: filepath.Join(dir, "spool.offset"),
+		maxBytes:   maxBytes,
+	}
+	if err = s.recover(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Internal method that loads the offset and counts the messages left. A partial last line from a crash is cut off.
+func (s *Spool) recover() error {
+	data, err := os.ReadFile(s.offsetPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read spool offset: %w", err)
+	}
+	if len(data) > 0 {
+		if s.offset, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			return fmt.Errorf("invalid spool offset: %w", err)
+		}
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat spool: %w", err)
+	}
+	if s.offset > info.Size() {
+		s.offset = 0 // Crashed after truncating but before saving the offset
+	}
+	if _, err = s.file.Seek(s.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek spool: %w", err)
+	}
+	reader := bufio.NewReader(s.file)
+	s.size = s.offset
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break // Anything left in line is a partial write
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read spool: %w", err)
+		}
+		s.size += int64(len(line))
+		s.depth++
+	}
+	if err = s.file.Truncate(s.size); err != nil {
+		return fmt.Errorf("failed to truncate spool: %w", err)
+	}
+	return nil
+}
+
+// Append a message to the end of the spool. The message must not contain newlines.
+func (s *Spool) Append(data []byte) error {
+	if bytes.IndexByte(data, '\n') >= 0 {
+		return errors.New("spooled message can't contain a newline")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size+int64(len(data))+1 > s.maxBytes {
+		return ErrFull
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		s.file.Truncate(s.size) // Don't leave a partial line behind
+		return fmt.Errorf("failed to append to spool: %w", err)
+	}
+	s.size += int64(len(data)) + 1
+	s.depth++
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool: %w", err)
+	}
+	return nil
+}
+
+// The number of messages waiting to be replayed
+func (s *Spool) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.depth
+}
+
+// Publish spooled messages in order until the spool is empty or publish fails. Returns the number replayed.
+// Only one Replay may run at a time.
+func (s *Spool) Replay(publish func(data []byte) error) (replayed int, err error) {
+	unsaved := 0 // Replayed since the offset was last saved
+	defer func() {
+		if unsaved == 0 {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if saveErr := s.saveOffset(); err == nil {
+			err = saveErr
+		}
+	}()
+	for {
+		s.mu.Lock()
+		offset, size := s.offset, s.size
+		if offset == size {
+			err = s.compact()
+			if err == nil {
+				unsaved = 0
+			}
+			s.mu.Unlock()
+			return replayed, err
+		}
+		s.mu.Unlock()
+
+		// Appends only touch the end of the file, so everything up to size can be read without the lock
+		reader := bufio.NewReader(io.NewSectionReader(s.file, offset, size-offset))
+		for offset < size {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return replayed, fmt.Errorf("failed to read spool: %w", err)
+			}
+			if err = publish(bytes.TrimSuffix(line, []byte{'\n'})); err != nil {
+				return replayed, err
+			}
+			offset += int64(len(line))
+			replayed++
+			unsaved++
+			save := unsaved == offsetSaveInterval
+			if save {
+				unsaved = 0
+			}
+			if err = s.advance(offset, save); err != nil {
+				return replayed, err
+			}
+		}
+	}
+}
+
+// Replay the spool every interval until ctx is canceled
+func (s *Spool) Run(ctx context.Context, interval time.Duration, publish func(data []byte) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.Depth() == 0 {
+				continue
+			}
+			replayed, err := s.Replay(publish)
+			if replayed > 0 {
+				zlog.Info().Int("replayed", replayed).Int("depth", s.Depth()).Msg("replayed spooled messages")
+			}
+			if err != nil {
+				zlog.Warn().Err(err).Int("depth", s.Depth()).Msg("unable to replay spool, trying again later")
+			}
+		}
+	}
+}
+
+// Close the spool file
+func (s *Spool) Close() error {
+	return s.file.Close()
+}
+
+// Internal method that moves the replayed position, and saves it if save is set
+func (s *Spool) advance(offset int64, save bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset = offset
+	s.depth--
+	if !save {
+		return nil
+	}
+	return s.saveOffset()
+}
+
+// Internal method that empties the log once everything was replayed. Caller must hold the lock.
+func (s *Spool) compact() error {
+	if s.size == 0 {
+		return nil
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate spool: %w", err)
+	}
+	s.size, s.offset = 0, 0
+	return s.saveOffset()
+}
+
+// Internal method that writes the offset file. It's written to a temp file and renamed over the old one,
+// so a crash leaves either the old or the new offset. Caller must hold the lock.
+func (s *Spool) saveOffset() error {
+	tmpPath := s.offsetPath + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to save spool offset: %w", err)
+	}
+	_, err = tmp.WriteString(strconv.FormatInt(s.offset, 10))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save spool offset: %w", err)
+	}
+	if err = os.Rename(tmpPath, s.offsetPath); err != nil {
+		return fmt.Errorf("failed to save spool offset: %w", err)
+	}
+	return syncDir(filepath.Dir(s.offsetPath)) // Make the rename itself durable
+}
+
+// Flush a directory's entries to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open spool dir: %w", err)
+	}
+	defer d.Close()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync spool dir: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package spool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSpool(t *testing.T, dir string, maxBytes int64) *Spool {
+	s, err := Open(dir, maxBytes)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// Messages are replayed in order and the spool is emptied afterwards
+func TestReplayInOrder(t *testing.T) {
+	s := newTestSpool(t, t.TempDir(), 1024)
+	for _, msg := range []string{"a", "b", "c"} {
+		assert.NoError(t, s.Append([]byte(msg)))
+	}
+	assert.Equal(t, 3, s.Depth())
+
+	var got []string
+	replayed, err := s.Replay(func(data []byte) error {
+		got = append(got, string(data))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	assert.Equal(t, []string{"a", "b", "c"}, got)
+	assert.Equal(t, 0, s.Depth())
+
+	info, err := s.file.Stat()
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+// A failed publish stops the replay and the next replay starts at the same message
+func TestReplayResumes(t *testing.T) {
+	s := newTestSpool(t, t.TempDir(), 1024)
+	for _, msg := range []string{"a", "b", "c"} {
+		assert.NoError(t, s.Append([]byte(msg)))
+	}
+
+	var got []string
+	replayed, err := s.Replay(func(data []byte) error {
+		if string(data) == "b" {
+			return errors.New("rabbitmq is down")
+		}
+		got = append(got, string(data))
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Equal(t, 2, s.Depth())
+
+	assert.NoError(t, s.Append([]byte("d"))) // Appended while replaying
+	_, err = s.Replay(func(data []byte) error {
+		got = append(got, string(data))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, got)
+}
+
+// A reopened spool skips what was already replayed and drops a partially written line
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 1024)
+	require.NoError(t, err)
+	for _, msg := range []string{"a", "b"} {
+		assert.NoError(t, s.Append([]byte(msg)))
+	}
+	_, err = s.Replay(func(data []byte) error {
+		if string(data) == "b" {
+			return errors.New("rabbitmq is down")
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	s.Close()
+
+	// Simulate a crash in the middle of an append
+	file, err := os.OpenFile(filepath.Join(dir, "spool.log"), os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString("parti")
+	require.NoError(t, err)
+	file.Close()
+
+	s = newTestSpool(t, dir, 1024)
+	assert.Equal(t, 1, s.Depth())
+	assert.NoError(t, s.Append([]byte("c")))
+
+	var got []string
+	_, err = s.Replay(func(data []byte) error {
+		got = append(got, string(data))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, got)
+}
+
+// The offset is only saved every offsetSaveInterval messages and when the replay stops
+func TestReplaySavesOffsetInBatches(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestSpool(t, dir, 1024)
+	for i := 0; i < offsetSaveInterval+2; i++ {
+		assert.NoError(t, s.Append([]byte("x")))
+	}
+	offsetPath := filepath.Join(dir, "spool.offset")
+	savedOffset := func() string {
+		data, _ := os.ReadFile(offsetPath)
+		return string(data)
+	}
+
+	published := 0
+	_, err := s.Replay(func(data []byte) error {
+		switch published {
+		case 1:
+			assert.NoFileExists(t, offsetPath)
+		case offsetSaveInterval + 1:
+			assert.Equal(t, strconv.Itoa(2*offsetSaveInterval), savedOffset())
+			return errors.New("rabbitmq is down")
+		}
+		published++
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, strconv.Itoa(2*(offsetSaveInterval+1)), savedOffset())
+	assert.NoFileExists(t, offsetPath+".tmp")
+}
+
+func TestAppendFull(t *testing.T) {
+	s := newTestSpool(t, t.TempDir(), 4)
+	assert.NoError(t, s.Append([]byte("ab")))
+	assert.ErrorIs(t, s.Append([]byte("c")), ErrFull)
+	assert.Equal(t, 1, s.Depth())
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+)
+
+// Records cache hits and misses
+type CacheMetrics interface {
+	IncrementCacheHit()
+	IncrementCacheMiss()
+}
+
+// A Store that keeps recent GetUserStats and GetMatches results in an LRU cache with a TTL.
+// Everything else goes straight to the underlying Store.
+type CachedStore struct {
+	Store   // interface
+	metrics CacheMetrics
+	size    int
+	ttl     time.Duration
+	mu      sync.Mutex
+	lru     *list.List // Most recently used at the front
+	entries map[cacheKey]*list.Element
+}
+
+// Stats and matches of the same user are cached separately
+type cacheKey struct {
+	matches bool
+	userId  int
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	found   bool
+	stats   models.UserStats
+	matches models.UserMatches
+	expires time.Time
+}
+
+// Create a new CachedStore in front of store that holds up to size entries for ttl
+func NewCachedStore(store Store, size int, ttl time.Duration, metrics CacheMetrics) *CachedStore {
+	return &CachedStore{
+		Store:   store,
+		metrics: metrics,
+		size:    size,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[cacheKey]*list.Element),
+	}
+}
+
+// Get user likes and dislikes. Returns found, user stats, and error.
+func (c *CachedStore) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	key := cacheKey{userId: userId}
+	if entry, ok := c.get(key); ok {
+		return entry.found, entry.stats, nil
+	}
+	found, stats, err := c.Store.GetUserStats(ctx, userId)
+	if err != nil {
+		return found, stats, err // Errors are not cached
+	}
+	c.put(&cacheEntry{key: key, found: found, stats: stats})
+	return found, stats, nil
+}
+
+// Get user matches list. Returns found, user matches, and error.
+func (c *CachedStore) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	key := cacheKey{matches: true, userId: userId}
+	if entry, ok := c.get(key); ok {
+		return entry.found, entry.matches, nil
+	}
+	found, matches, err := c.Store.GetMatches(ctx, userId)
+	if err != nil {
+		return found, matches, err
+	}
+	c.put(&cacheEntry{key: key, found: found, matches: matches})
+	return found, matches, nil
+}
+
+// Drop a user's stats and matches from the cache
+func (c *CachedStore) Invalidate(userId int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range []cacheKey{{userId: userId}, {matches: true, userId: userId}} {
+		if elem, ok := c.entries[key]; ok {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Internal method that returns a fresh entry and marks it as recently used
+func (c *CachedStore) get(key cacheKey) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if ok && time.Now().Before(elem.Value.(*cacheEntry).expires) {
+		c.lru.MoveToFront(elem)
+		c.metrics.IncrementCacheHit()
+		return elem.Value.(*cacheEntry), true
+	}
+	if ok { // Expired
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.metrics.IncrementCacheMiss()
+	return nil, false
+}
+
+// Internal method that adds an entry and evicts the least recently used one if the cache is full
+func (c *CachedStore) put(entry *cacheEntry) {
+	entry.expires = time.Now().Add(c.ttl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockMetrics "github.com/DennisPing/cs6650-twinder-a3/httpserver/metrics/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/httpserver/store/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// The second read is served from the cache
+func TestCachedStoreHit(t *testing.T) {
+	ctx := context.Background()
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().GetUserStats(ctx, 1234).Return(true, models.UserStats{NumLikes: 3}, nil).Once()
+	mockStore.EXPECT().GetMatches(ctx, 1234).Return(true, models.UserMatches{MatchList: []int{5678}}, nil).Once()
+	metrics := mockMetrics.NewMetrics(t)
+	metrics.EXPECT().IncrementCacheMiss().Return().Twice()
+	metrics.EXPECT().IncrementCacheHit().Return().Twice()
+	cache := NewCachedStore(mockStore, 10, time.Minute, metrics)
+
+	for i := 0; i < 2; i++ {
+		found, stats, err := cache.GetUserStats(ctx, 1234)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, 3, stats.NumLikes)
+
+		found, matches, err := cache.GetMatches(ctx, 1234)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []int{5678}, matches.MatchList)
+	}
+}
+
+// Expired, evicted, and invalidated entries are read from the store again
+func TestCachedStoreMiss(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		size  int
+		ttl   time.Duration
+		evict func(c *CachedStore)
+	}{
+		{
+			name:  "expired",
+			size:  10,
+			ttl:   time.Millisecond,
+			evict: func(c *CachedStore) { time.Sleep(5 * time.Millisecond) },
+		},
+		{
+			name:  "evicted",
+			size:  1,
+			ttl:   time.Minute,
+			evict: func(c *CachedStore) { c.GetUserStats(ctx, 2) },
+		},
+		{
+			name:  "invalidated",
+			size:  10,
+			ttl:   time.Minute,
+			evict: func(c *CachedStore) { c.Invalidate(1) },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := mocks.NewStore(t)
+			mockStore.EXPECT().GetUserStats(ctx, 1).Return(true, models.UserStats{}, nil).Twice()
+			mockStore.EXPECT().GetUserStats(ctx, 2).Return(false, models.UserStats{}, nil).Maybe()
+			metrics := mockMetrics.NewMetrics(t)
+			metrics.EXPECT().IncrementCacheMiss().Return()
+			cache := NewCachedStore(mockStore, tc.size, tc.ttl, metrics)
+
+			cache.GetUserStats(ctx, 1)
+			tc.evict(cache)
+			cache.GetUserStats(ctx, 1)
+		})
+	}
+}
+
+// Errors are not cached
+func TestCachedStoreError(t *testing.T) {
+	ctx := context.Background()
+	mockStore := mocks.NewStore(t)
+	mockStore.EXPECT().GetMatches(ctx, 1).Return(false, models.UserMatches{}, errors.New("aws died")).Twice()
+	metrics := mockMetrics.NewMetrics(t)
+	metrics.EXPECT().IncrementCacheMiss().Return().Twice()
+	cache := NewCachedStore(mockStore, 10, time.Minute, metrics)
+
+	cache.GetMatches(ctx, 1)
+	_, _, err := cache.GetMatches(ctx, 1)
+	assert.EqualError(t, err, "aws died")
+}
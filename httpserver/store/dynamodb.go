@@ -3,9 +3,16 @@ package store
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/DennisPing/cs6650-twinder-a3/lib/dynamo"
 	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
@@ -16,29 +23,58 @@ import (
 //go:generate mockery --name=DynamoClienter --filename=mock_database.go
 type DynamoClienter interface {
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
 	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
+const (
+	commentsTable = "SwipeComments" // Comments are partitioned by the recipient's userId so they don't need sharding
+
+	batchGetSize     = 100 // Max keys per BatchGetItem call
+	batchGetAttempts = 5   // How many times unprocessed keys are requested again
+	batchGetBackoff  = 50 * time.Millisecond
+)
+
+// A commentId is the unix nano time of the comment, then the swipe id or the swiper's userId
+var commentIdPattern = regexp.MustCompile(`^[0-9]{19}#[0-9a-v]+$`)
+
 // Database client that communicates with DynamoDB via interface
 type DatabaseClient struct {
 	Client DynamoClienter // Interface
+	Router shard.Router   // Interface, nil means the default shard ranges
 }
 
-// Create a new DatabaseClient that has a DynamoDB client
-func NewDatabaseClient() (*DatabaseClient, error) {
+// Create a new DatabaseClient that has a DynamoDB client for the tables in region
+func NewDatabaseClient(region string, router shard.Router) (*DatabaseClient, error) {
 	// LoadDefaultConfig automatically loads env variables and ~/.aws/credentials
 	cfg, err := config.LoadDefaultConfig(
 		context.Background(),
-		config.WithRegion("us-east-2"),
+		config.WithRegion(region),
 	)
 	if err != nil {
 		return nil, err
 	}
 	return &DatabaseClient{
 		Client: dynamodb.NewFromConfig(cfg),
+		Router: router,
 	}, nil
 }
 
+// Get the shard layout, the default shard ranges if there is no Router
+func (d *DatabaseClient) router() shard.Router {
+	if d.Router == nil {
+		return defaultRouter
+	}
+	return d.Router
+}
+
+// Get the DynamoDB table a user lives in
+func (d *DatabaseClient) table(userId int) string {
+	return d.router().Table(userId)
+}
+
 // Get user likes and dislikes. Returns found, user stats, and error.
 func (d *DatabaseClient) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
 	item, err := d.getItem(ctx, userId)
@@ -74,16 +110,154 @@ func (d *DatabaseClient) GetMatches(ctx context.Context, userId int) (bool, mode
 	return false, userMatches, nil // not found
 }
 
+// Get the stats and matches of many users at once. Users that don't exist are left out of the result.
+func (d *DatabaseClient) BatchGetUsers(ctx context.Context, userIds []int) (map[int]models.UserRecord, error) {
+	// Every user is read from their table, and also from their old table while being moved
+	keys := make([]tableKey, 0, len(userIds))
+	for _, userId := range uniqueIds(userIds) {
+		keys = append(keys, tableKey{table: d.table(userId), userId: userId})
+		if migrating, ok := d.Router.(*shard.MigratingRouter); ok {
+			if previous, moving := migrating.PreviousTable(userId); moving {
+				keys = append(keys, tableKey{table: previous, userId: userId})
+			}
+		}
+	}
+
+	items := make(map[int]*models.DynamoUserStats, len(userIds))
+	for start := 0; start < len(keys); start += batchGetSize {
+		end := start + batchGetSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := d.batchGetItems(ctx, keys[start:end], items); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make(map[int]models.UserRecord, len(items))
+	for userId, item := range items {
+		records[userId] = models.UserRecord{
+			Stats: models.UserStats{
+				NumLikes:    item.NumLikes,
+				NumDislikes: item.NumDislikes,
+			},
+			Matches: models.UserMatches{
+				MatchList: item.MatchList,
+			},
+		}
+	}
+	return records, nil
+}
+
+// A user row in a specific table
+type tableKey struct {
+	table  string
+	userId int
+}
+
+// Internal method that reads up to batchGetSize rows grouped by table and merges them into items.
+// Keys that DynamoDB couldn't process, usually because of throttling, are requested again after backing off.
+func (d *DatabaseClient) batchGetItems(ctx context.Context, keys []tableKey, items map[int]*models.DynamoUserStats) error {
+	requestItems := make(map[string]types.KeysAndAttributes)
+	for _, key := range keys {
+		request := requestItems[key.table]
+		request.Keys = append(request.Keys, dynamo.UserKey(key.userId))
+		requestItems[key.table] = request
+	}
+
+	backoff := batchGetBackoff
+	for attempt := 0; len(requestItems) > 0; attempt++ {
+		if attempt == batchGetAttempts {
+			return fmt.Errorf("failed to batch get items: keys still unprocessed after %d attempts", attempt)
+		}
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		resp, err := d.Client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: requestItems,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to batch get items: %w", err)
+		}
+		for _, tableItems := range resp.Responses {
+			for _, tableItem := range tableItems {
+				item := &models.DynamoUserStats{}
+				if err = attributevalue.UnmarshalMap(tableItem, item); err != nil {
+					return fmt.Errorf("failed to unmarshal item: %w", err)
+				}
+				items[item.UserId] = dynamo.MergeItems(items[item.UserId], item)
+			}
+		}
+		requestItems = resp.UnprocessedKeys
+	}
+	return nil
+}
+
+// Get the comments a user received, newest first. The cursor is empty for the first page.
+func (d *DatabaseClient) GetComments(ctx context.Context, userId, limit int, cursor string) (models.UserComments, error) {
+	var userComments models.UserComments
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(commentsTable),
+		KeyConditionExpression: aws.String("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberN{Value: strconv.Itoa(userId)},
+		},
+		ScanIndexForward: aws.Bool(false), // Newest first
+		Limit:            aws.Int32(int32(limit)),
+	}
+	if cursor != "" {
+		position, err := decodeCursor(cursor)
+		if err != nil {
+			return userComments, err
+		}
+		// The cursor must belong to this user and hold a commentId, anything else DynamoDB rejects with a 500
+		owner, commentId, found := strings.Cut(position, ":")
+		if !found || owner != strconv.Itoa(userId) || !commentIdPattern.MatchString(commentId) {
+			return userComments, ErrInvalidCursor
+		}
+		input.ExclusiveStartKey = dynamo.UserKey(userId)
+		input.ExclusiveStartKey["commentId"] = &types.AttributeValueMemberS{Value: commentId}
+	}
+
+	resp, err := d.Client.Query(ctx, input)
+	if err != nil {
+		return userComments, fmt.Errorf("failed to query comments: %w", err)
+	}
+	var items []models.DynamoComment
+	if err = attributevalue.UnmarshalListOfMaps(resp.Items, &items); err != nil {
+		return userComments, fmt.Errorf("failed to unmarshal comments: %w", err)
+	}
+	userComments.Comments = make([]models.Comment, 0, len(items))
+	for _, item := range items {
+		userComments.Comments = append(userComments.Comments, models.Comment{
+			From:      item.From,
+			Comment:   item.Comment,
+			Direction: item.Direction,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+	// DynamoDB only returns a LastEvaluatedKey if there may be more results
+	if commentId, ok := resp.LastEvaluatedKey["commentId"].(*types.AttributeValueMemberS); ok {
+		userComments.NextCursor = encodeCursor(fmt.Sprintf("%d:%s", userId, commentId.Value))
+	}
+	return userComments, nil
+}
+
 // Update a user's stats. If userId doesn't exist, then a new entry is created
 func (d *DatabaseClient) UpdateUserStats(ctx context.Context, userId, swipee int, swipeDir string) error {
-	tableName := getTableShard(userId)
+	tableName := d.table(userId)
 	var update expression.UpdateBuilder
 	switch swipeDir {
 	case "right":
 		update = update.
 			Add(expression.Name("numLikes"), expression.Value(1)).
 			Set(expression.Name("numDislikes"), expression.IfNotExists(expression.Name("numDislikes"), expression.Value(0))).
-			Add(expression.Name("matchList"), expression.Value(
+			Add(expression.Name("likedList"), expression.Value(
 				&types.AttributeValueMemberNS{Value: []string{strconv.Itoa(swipee)}},
 			))
 	case "left":
@@ -100,6 +274,7 @@ func (d *DatabaseClient) UpdateUserStats(ctx context.Context, userId, swipee int
 	}
 
 	// Execute the UpdateItem operation
+	_, span := tracing.StartDynamoDB(ctx, "UpdateItem", tableName)
 	_, err = d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		Key: map[string]types.AttributeValue{
 			"userId": &types.AttributeValueMemberN{Value: strconv.Itoa(userId)},
@@ -110,30 +285,18 @@ func (d *DatabaseClient) UpdateUserStats(ctx context.Context, userId, swipee int
 		UpdateExpression:          expr.Update(),
 		ReturnValues:              types.ReturnValueNone,
 	})
+	tracing.RecordError(span, err)
+	span.End()
 	if err != nil {
 		return fmt.Errorf("failed to update item: %w", err)
 	}
+	if swipeDir == "right" {
+		return dynamo.AddMatchIfMutual(ctx, d.Client, d.router(), userId, swipee)
+	}
 	return nil
 }
 
-// Internal method that gets the entire row from DynamoDB
+// Internal method that gets the entire row from DynamoDB, from both tables while a user is being moved
 func (d *DatabaseClient) getItem(ctx context.Context, userId int) (*models.DynamoUserStats, error) {
-	tableName := getTableShard(userId)
-	resp, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: &tableName,
-		Key: map[string]types.AttributeValue{
-			"userId": &types.AttributeValueMemberN{Value: strconv.Itoa(userId)},
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get item: %w", err)
-	}
-	if resp.Item == nil { // Item not found
-		return nil, nil
-	}
-	dynamoItem := &models.DynamoUserStats{}
-	if err = attributevalue.UnmarshalMap(resp.Item, &dynamoItem); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal item: %w", err)
-	}
-	return dynamoItem, nil
+	return dynamo.GetUserItem(ctx, d.Client, d.router(), userId)
 }
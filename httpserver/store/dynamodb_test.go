@@ -6,6 +6,9 @@ import (
 	"testing"
 
 	"github.com/DennisPing/cs6650-twinder-a3/httpserver/store/mocks"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/dynamo"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
@@ -99,6 +102,176 @@ func TestGetUserStatsError(t *testing.T) {
 	}
 }
 
+// A user who is being moved to another shard is read from both tables, old table first
+func TestGetMatchesWhileMigrating(t *testing.T) {
+	ctx := context.Background()
+	mockDynamo := mocks.NewDynamoClienter(t)
+	router, err := shard.NewRouter(shard.Config{
+		Ranges:   []shard.Range{{Max: 2000, Table: "SwipeData1"}, {Table: "SwipeData2"}},
+		Previous: &shard.Config{Ranges: []shard.Range{{Table: "SwipeData1"}}},
+	})
+	assert.NoError(t, err)
+
+	var tables []string
+	mockDynamo.EXPECT().GetItem(ctx, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return *input.TableName == "SwipeData2"
+	}), mock.Anything).Run(func(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) {
+		tables = append(tables, *input.TableName)
+	}).Return(&dynamodb.GetItemOutput{
+		Item: map[string]types.AttributeValue{
+			"numLikes":  &types.AttributeValueMemberN{Value: "1"},
+			"matchList": &types.AttributeValueMemberNS{Value: []string{"7"}},
+		},
+	}, nil)
+	mockDynamo.EXPECT().GetItem(ctx, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return *input.TableName == "SwipeData1"
+	}), mock.Anything).Run(func(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) {
+		tables = append(tables, *input.TableName)
+	}).Return(&dynamodb.GetItemOutput{
+		Item: map[string]types.AttributeValue{
+			"numLikes":  &types.AttributeValueMemberN{Value: "2"},
+			"matchList": &types.AttributeValueMemberNS{Value: []string{"5", "7"}},
+		},
+	}, nil)
+	databaseClient := DatabaseClient{
+		Client: mockDynamo,
+		Router: router,
+	}
+
+	found, matches, err := databaseClient.GetMatches(ctx, 3000)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []int{5, 7}, matches.MatchList)
+	assert.Equal(t, []string{"SwipeData1", "SwipeData2"}, tables) // Old table first so a move in between can't hide the user
+
+	found, stats, err := databaseClient.GetUserStats(ctx, 3000)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 3, stats.NumLikes)
+}
+
+// Users are read from their own shard and keys that DynamoDB couldn't process are requested again
+func TestBatchGetUsers(t *testing.T) {
+	ctx := context.Background()
+	mockDynamo := mocks.NewDynamoClienter(t)
+	router, err := shard.NewRangeRouter([]shard.Range{{Max: 2000, Table: "SwipeData1"}, {Table: "SwipeData2"}})
+	assert.NoError(t, err)
+
+	// The first call only gets to one table
+	mockDynamo.EXPECT().BatchGetItem(ctx, mock.MatchedBy(func(input *dynamodb.BatchGetItemInput) bool {
+		return len(input.RequestItems) == 2 &&
+			len(input.RequestItems["SwipeData1"].Keys) == 1 &&
+			len(input.RequestItems["SwipeData2"].Keys) == 2 // 3000 was requested twice
+	}), mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{
+			"SwipeData1": {{
+				"userId":   &types.AttributeValueMemberN{Value: "1000"},
+				"numLikes": &types.AttributeValueMemberN{Value: "4"},
+			}},
+		},
+		UnprocessedKeys: map[string]types.KeysAndAttributes{
+			"SwipeData2": {Keys: []map[string]types.AttributeValue{dynamo.UserKey(3000), dynamo.UserKey(4000)}},
+		},
+	}, nil).Once()
+	mockDynamo.EXPECT().BatchGetItem(ctx, mock.MatchedBy(func(input *dynamodb.BatchGetItemInput) bool {
+		return len(input.RequestItems) == 1 && len(input.RequestItems["SwipeData2"].Keys) == 2
+	}), mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{
+			"SwipeData2": {{
+				"userId":    &types.AttributeValueMemberN{Value: "3000"},
+				"matchList": &types.AttributeValueMemberNS{Value: []string{"1000"}},
+			}},
+		},
+	}, nil).Once()
+	databaseClient := DatabaseClient{
+		Client: mockDynamo,
+		Router: router,
+	}
+
+	records, err := databaseClient.BatchGetUsers(ctx, []int{1000, 3000, 4000, 3000})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2) // 4000 doesn't exist
+	assert.Equal(t, 4, records[1000].Stats.NumLikes)
+	assert.Equal(t, []int{1000}, records[3000].Matches.MatchList)
+}
+
+// A canceled request stops waiting out the backoff for unprocessed keys
+func TestBatchGetUsersCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockDynamo := mocks.NewDynamoClienter(t)
+	mockDynamo.EXPECT().BatchGetItem(ctx, mock.Anything, mock.Anything).Run(
+		func(ctx context.Context, input *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) {
+			cancel()
+		}).Return(&dynamodb.BatchGetItemOutput{
+		UnprocessedKeys: map[string]types.KeysAndAttributes{
+			"SwipeData": {Keys: []map[string]types.AttributeValue{dynamo.UserKey(1000)}},
+		},
+	}, nil).Once()
+	databaseClient := DatabaseClient{
+		Client: mockDynamo,
+	}
+
+	_, err := databaseClient.BatchGetUsers(ctx, []int{1000})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// Get comments happy path
+func TestGetComments(t *testing.T) {
+	ctx := context.Background()
+	mockDynamo := mocks.NewDynamoClienter(t)
+
+	mockDynamo.EXPECT().Query(ctx, mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			{
+				"userId":    &types.AttributeValueMemberN{Value: "5678"},
+				"commentId": &types.AttributeValueMemberS{Value: "1687046400000000000#1234"},
+				"from":      &types.AttributeValueMemberN{Value: "1234"},
+				"comment":   &types.AttributeValueMemberS{Value: "hello"},
+			},
+		},
+		LastEvaluatedKey: map[string]types.AttributeValue{
+			"userId":    &types.AttributeValueMemberN{Value: "5678"},
+			"commentId": &types.AttributeValueMemberS{Value: "1687046400000000000#1234"},
+		},
+	}, nil)
+	databaseClient := DatabaseClient{
+		Client: mockDynamo,
+	}
+	comments, err := databaseClient.GetComments(ctx, 5678, 1, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, comments.Comments, 1)
+	assert.Equal(t, 1234, comments.Comments[0].From)
+	assert.Equal(t, "hello", comments.Comments[0].Comment)
+
+	// The next page starts after the last comment
+	position, err := decodeCursor(comments.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "5678:1687046400000000000#1234", position)
+}
+
+// Cursors that DynamoDB would reject never reach it
+func TestGetCommentsInvalidCursor(t *testing.T) {
+	tests := []struct {
+		name     string
+		position string
+	}{
+		{name: "another user's cursor", position: "1234:1687046400000000000#1234"},
+		{name: "no userId", position: "1687046400000000000#1234"},
+		{name: "not a commentId", position: "5678:hello"},
+		{name: "no swipe id", position: "5678:1687046400000000000#"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			databaseClient := DatabaseClient{
+				Client: mocks.NewDynamoClienter(t), // Strict, any Query fails the test
+			}
+			_, err := databaseClient.GetComments(context.Background(), 5678, 10, encodeCursor(tc.position))
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
+
 // Update user stats happy path
 func TestUpdateUserStats(t *testing.T) {
 	tests := []struct {
@@ -106,12 +279,26 @@ func TestUpdateUserStats(t *testing.T) {
 		userId   int
 		swipee   int
 		swipeDir string
+		matchErr error // the result of the mutual match transaction
 	}{
 		{
-			name:     "swipe right",
+			name:     "swipe right mutual match",
 			userId:   1234,
 			swipee:   5678,
 			swipeDir: "right",
+			matchErr: nil,
+		},
+		{
+			name:     "swipe right no match yet",
+			userId:   1234,
+			swipee:   5678,
+			swipeDir: "right",
+			matchErr: &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("ConditionalCheckFailed")},
+					{Code: aws.String("None")},
+				},
+			},
 		},
 		{
 			name:     "swipe left",
@@ -129,6 +316,11 @@ func TestUpdateUserStats(t *testing.T) {
 			mockDynamoClient.EXPECT().
 				UpdateItem(ctx, mock.Anything, mock.Anything).
 				Return(&dynamodb.UpdateItemOutput{}, nil)
+			if tc.swipeDir == "right" {
+				mockDynamoClient.EXPECT().
+					TransactWriteItems(ctx, mock.Anything, mock.Anything).
+					Return(&dynamodb.TransactWriteItemsOutput{}, tc.matchErr)
+			}
 
 			databaseClient := DatabaseClient{
 				Client: mockDynamoClient,
@@ -149,6 +341,7 @@ func TestUpdateUserStatsError(t *testing.T) {
 		swipee            int
 		swipeDir          string
 		mockInternalError error
+		mockMatchError    error
 		expectedErrorMsg  string // the high level error message
 	}{
 		{
@@ -159,6 +352,14 @@ func TestUpdateUserStatsError(t *testing.T) {
 			mockInternalError: errors.New("aws died"),
 			expectedErrorMsg:  "failed to update item: aws died",
 		},
+		{
+			name:             "dynamo transaction error",
+			userId:           1234,
+			swipee:           5678,
+			swipeDir:         "right",
+			mockMatchError:   errors.New("aws died"),
+			expectedErrorMsg: "failed to add match: aws died",
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -168,6 +369,11 @@ func TestUpdateUserStatsError(t *testing.T) {
 			mockDynamoClient.EXPECT().
 				UpdateItem(ctx, mock.Anything, mock.Anything).
 				Return(&dynamodb.UpdateItemOutput{}, tc.mockInternalError)
+			if tc.mockMatchError != nil {
+				mockDynamoClient.EXPECT().
+					TransactWriteItems(ctx, mock.Anything, mock.Anything).
+					Return(nil, tc.mockMatchError)
+			}
 
 			databaseClient := DatabaseClient{
 				Client: mockDynamoClient,
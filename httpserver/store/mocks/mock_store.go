@@ -0,0 +1,268 @@
+// Code generated by mockery v2.27.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Store is an autogenerated mock type for the Store type
+type Store struct {
+	mock.Mock
+}
+
+type Store_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Store) EXPECT() *Store_Expecter {
+	return &Store_Expecter{mock: &_m.Mock}
+}
+
+// BatchGetUsers provides a mock function with given fields: ctx, userIds
+func (_m *Store) BatchGetUsers(ctx context.Context, userIds []int) (map[int]models.UserRecord, error) {
+	ret := _m.Called(ctx, userIds)
+
+	var r0 map[int]models.UserRecord
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int) (map[int]models.UserRecord, error)); ok {
+		return rf(ctx, userIds)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int) map[int]models.UserRecord); ok {
+		r0 = rf(ctx, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int]models.UserRecord)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int) error); ok {
+		r1 = rf(ctx, userIds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Store_BatchGetUsers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BatchGetUsers'
+type Store_BatchGetUsers_Call struct {
+	*mock.Call
+}
+
+// BatchGetUsers is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userIds []int
+func (_e *Store_Expecter) BatchGetUsers(ctx interface{}, userIds interface{}) *Store_BatchGetUsers_Call {
+	return &Store_BatchGetUsers_Call{Call: _e.mock.On("BatchGetUsers", ctx, userIds)}
+}
+
+func (_c *Store_BatchGetUsers_Call) Run(run func(ctx context.Context, userIds []int)) *Store_BatchGetUsers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]int))
+	})
+	return _c
+}
+
+func (_c *Store_BatchGetUsers_Call) Return(_a0 map[int]models.UserRecord, _a1 error) *Store_BatchGetUsers_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Store_BatchGetUsers_Call) RunAndReturn(run func(context.Context, []int) (map[int]models.UserRecord, error)) *Store_BatchGetUsers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetComments provides a mock function with given fields: ctx, userId, limit, cursor
+func (_m *Store) GetComments(ctx context.Context, userId int, limit int, cursor string) (models.UserComments, error) {
+	ret := _m.Called(ctx, userId, limit, cursor)
+
+	var r0 models.UserComments
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string) (models.UserComments, error)); ok {
+		return rf(ctx, userId, limit, cursor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, string) models.UserComments); ok {
+		r0 = rf(ctx, userId, limit, cursor)
+	} else {
+		r0 = ret.Get(0).(models.UserComments)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, string) error); ok {
+		r1 = rf(ctx, userId, limit, cursor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Store_GetComments_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetComments'
+type Store_GetComments_Call struct {
+	*mock.Call
+}
+
+// GetComments is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+//   - limit int
+//   - cursor string
+func (_e *Store_Expecter) GetComments(ctx interface{}, userId interface{}, limit interface{}, cursor interface{}) *Store_GetComments_Call {
+	return &Store_GetComments_Call{Call: _e.mock.On("GetComments", ctx, userId, limit, cursor)}
+}
+
+func (_c *Store_GetComments_Call) Run(run func(ctx context.Context, userId int, limit int, cursor string)) *Store_GetComments_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int), args[2].(int), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *Store_GetComments_Call) Return(_a0 models.UserComments, _a1 error) *Store_GetComments_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Store_GetComments_Call) RunAndReturn(run func(context.Context, int, int, string) (models.UserComments, error)) *Store_GetComments_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetMatches provides a mock function with given fields: ctx, userId
+func (_m *Store) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 bool
+	var r1 models.UserMatches
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (bool, models.UserMatches, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) bool); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) models.UserMatches); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Get(1).(models.UserMatches)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int) error); ok {
+		r2 = rf(ctx, userId)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Store_GetMatches_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMatches'
+type Store_GetMatches_Call struct {
+	*mock.Call
+}
+
+// GetMatches is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *Store_Expecter) GetMatches(ctx interface{}, userId interface{}) *Store_GetMatches_Call {
+	return &Store_GetMatches_Call{Call: _e.mock.On("GetMatches", ctx, userId)}
+}
+
+func (_c *Store_GetMatches_Call) Run(run func(ctx context.Context, userId int)) *Store_GetMatches_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Store_GetMatches_Call) Return(_a0 bool, _a1 models.UserMatches, _a2 error) *Store_GetMatches_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Store_GetMatches_Call) RunAndReturn(run func(context.Context, int) (bool, models.UserMatches, error)) *Store_GetMatches_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetUserStats provides a mock function with given fields: ctx, userId
+func (_m *Store) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 bool
+	var r1 models.UserStats
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (bool, models.UserStats, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) bool); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) models.UserStats); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Get(1).(models.UserStats)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int) error); ok {
+		r2 = rf(ctx, userId)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Store_GetUserStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetUserStats'
+type Store_GetUserStats_Call struct {
+	*mock.Call
+}
+
+// GetUserStats is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId int
+func (_e *Store_Expecter) GetUserStats(ctx interface{}, userId interface{}) *Store_GetUserStats_Call {
+	return &Store_GetUserStats_Call{Call: _e.mock.On("GetUserStats", ctx, userId)}
+}
+
+func (_c *Store_GetUserStats_Call) Run(run func(ctx context.Context, userId int)) *Store_GetUserStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Store_GetUserStats_Call) Return(_a0 bool, _a1 models.UserStats, _a2 error) *Store_GetUserStats_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Store_GetUserStats_Call) RunAndReturn(run func(context.Context, int) (bool, models.UserStats, error)) *Store_GetUserStats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+type mockConstructorTestingTNewStore interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewStore creates a new instance of Store. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewStore(t mockConstructorTestingTNewStore) *Store {
+	mock := &Store{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
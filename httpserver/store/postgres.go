@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/schema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Database client that reads what the consumer writes to PostgreSQL
+type PostgresClient struct {
+	Pool *pgxpool.Pool
+}
+
+// Create a new PostgresClient. The tables are created if they don't exist, so either service can start first.
+func NewPostgresClient(url string) (*PostgresClient, error) {
+	if url == "" {
+		return nil, errors.New("you forgot to set the POSTGRES_URL env variable")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = pool.Exec(ctx, schema.Postgres); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return &PostgresClient{
+		Pool: pool,
+	}, nil
+}
+
+// Get user likes and dislikes. Returns found, user stats, and error.
+func (p *PostgresClient) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	var userStats models.UserStats
+	err := p.Pool.QueryRow(ctx,
+		`SELECT num_likes, num_dislikes FROM user_stats WHERE user_id = $1`, userId,
+	).Scan(&userStats.NumLikes, &userStats.NumDislikes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, userStats, nil // not found
+	}
+	if err != nil {
+		return false, userStats, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	return true, userStats, nil
+}
+
+// Get user matches list. Returns found, user matches, and error.
+func (p *PostgresClient) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	var userMatches models.UserMatches
+	found, _, err := p.GetUserStats(ctx, userId)
+	if err != nil || !found {
+		return false, userMatches, err
+	}
+
+	rows, err := p.Pool.Query(ctx,
+		`SELECT match_id FROM matches WHERE user_id = $1 ORDER BY match_id`, userId)
+	if err != nil {
+		return false, userMatches, fmt.Errorf("failed to get matches: %w", err)
+	}
+	matchList, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return false, userMatches, fmt.Errorf("failed to get matches: %w", err)
+	}
+	userMatches.MatchList = matchList
+	return true, userMatches, nil
+}
+
+// Get the stats and matches of many users at once. Users that don't exist are left out of the result.
+func (p *PostgresClient) BatchGetUsers(ctx context.Context, userIds []int) (map[int]models.UserRecord, error) {
+	userIds = uniqueIds(userIds)
+	records := make(map[int]models.UserRecord, len(userIds))
+
+	rows, err := p.Pool.Query(ctx,
+		`SELECT user_id, num_likes, num_dislikes FROM user_stats WHERE user_id = ANY($1)`, userIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	var userId, matchId int
+	var record models.UserRecord
+	_, err = pgx.ForEachRow(rows, []any{&userId, &record.Stats.NumLikes, &record.Stats.NumDislikes}, func() error {
+		records[userId] = record
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+
+	rows, err = p.Pool.Query(ctx,
+		`SELECT user_id, match_id FROM matches WHERE user_id = ANY($1) ORDER BY user_id, match_id`, userIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matches: %w", err)
+	}
+	_, err = pgx.ForEachRow(rows, []any{&userId, &matchId}, func() error {
+		if record, ok := records[userId]; ok { // Same as GetMatches, users without stats don't exist
+			record.Matches.MatchList = append(record.Matches.MatchList, matchId)
+			records[userId] = record
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matches: %w", err)
+	}
+	return records, nil
+}
+
+// Get the comments a user received, newest first. The cursor is empty for the first page.
+func (p *PostgresClient) GetComments(ctx context.Context, userId, limit int, cursor string) (models.UserComments, error) {
+	var userComments models.UserComments
+	beforeId := int64(math.MaxInt64)
+	if cursor != "" {
+		position, err := decodeCursor(cursor)
+		if err != nil {
+			return userComments, err
+		}
+		if beforeId, err = strconv.ParseInt(position, 10, 64); err != nil {
+			return userComments, ErrInvalidCursor
+		}
+	}
+
+	// Fetch one extra row to find out if there is a next page
+	rows, err := p.Pool.Query(ctx, `
+		SELECT id, from_id, comment, direction, to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')
+		FROM comments WHERE user_id = $1 AND id < $2 ORDER BY id DESC LIMIT $3`,
+		userId, beforeId, limit+1)
+	if err != nil {
+		return userComments, fmt.Errorf("failed to get comments: %w", err)
+	}
+	userComments.Comments = make([]models.Comment, 0, limit)
+	var id, lastId int64
+	var comment models.Comment
+	_, err = pgx.ForEachRow(rows, []any{&id, &comment.From, &comment.Comment, &comment.Direction, &comment.CreatedAt}, func() error {
+		if len(userComments.Comments) == limit {
+			userComments.NextCursor = encodeCursor(strconv.FormatInt(lastId, 10))
+			return nil
+		}
+		userComments.Comments = append(userComments.Comments, comment)
+		lastId = id
+		return nil
+	})
+	if err != nil {
+		return userComments, fmt.Errorf("failed to get comments: %w", err)
+	}
+	return userComments, nil
+}
+
+// Close the connection pool
+func (p *PostgresClient) Close() error {
+	p.Pool.Close()
+	return nil
+}
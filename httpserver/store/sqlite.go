@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Max userIds per query, well below SQLite's limit on bound parameters
+const sqliteBatchSize = 500
+
+// Database client that stores everything in a local SQLite file
+type SqliteClient struct {
+	DB *sql.DB
+}
+
+// Create a new SqliteClient. The database file and tables are created if they don't exist.
+func NewSqliteClient(path string) (*SqliteClient, error) {
+	if path == "" {
+		path = "twinder.db"
+	}
+	// WAL mode lets the httpserver read while the consumer writes to the same file
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.Exec(schema.Sqlite); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return &SqliteClient{
+		DB: db,
+	}, nil
+}
+
+// Get user likes and dislikes. Returns found, user stats, and error.
+func (s *SqliteClient) GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error) {
+	var userStats models.UserStats
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT num_likes, num_dislikes FROM user_stats WHERE user_id = ?`, userId,
+	).Scan(&userStats.NumLikes, &userStats.NumDislikes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, userStats, nil // not found
+	}
+	if err != nil {
+		return false, userStats, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	return true, userStats, nil
+}
+
+// Get user matches list. Returns found, user matches, and error.
+func (s *SqliteClient) GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error) {
+	var userMatches models.UserMatches
+	found, _, err := s.GetUserStats(ctx, userId)
+	if err != nil || !found {
+		return false, userMatches, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT match_id FROM matches WHERE user_id = ? ORDER BY match_id`, userId)
+	if err != nil {
+		return false, userMatches, fmt.Errorf("failed to get matches: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var matchId int
+		if err = rows.Scan(&matchId); err != nil {
+			return false, userMatches, fmt.Errorf("failed to scan match: %w", err)
+		}
+		userMatches.MatchList = append(userMatches.MatchList, matchId)
+	}
+	if err = rows.Err(); err != nil {
+		return false, userMatches, fmt.Errorf("failed to get matches: %w", err)
+	}
+	return true, userMatches, nil
+}
+
+// Get the stats and matches of many users at once. Users that don't exist are left out of the result.
+func (s *SqliteClient) BatchGetUsers(ctx context.Context, userIds []int) (map[int]models.UserRecord, error) {
+	userIds = uniqueIds(userIds)
+	records := make(map[int]models.UserRecord, len(userIds))
+	for start := 0; start < len(userIds); start += sqliteBatchSize {
+		end := start + sqliteBatchSize
+		if end > len(userIds) {
+			end = len(userIds)
+		}
+		if err := s.batchGetUsers(ctx, userIds[start:end], records); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// Internal method that reads the stats and matches of up to sqliteBatchSize users into records
+func (s *SqliteClient) batchGetUsers(ctx context.Context, userIds []int, records map[int]models.UserRecord) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(userIds)), ",")
+	args := make([]interface{}, len(userIds))
+	for i, userId := range userIds {
+		args[i] = userId
+	}
+
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT user_id, num_likes, num_dislikes FROM user_stats WHERE user_id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get user stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userId int
+		var record models.UserRecord
+		if err = rows.Scan(&userId, &record.Stats.NumLikes, &record.Stats.NumDislikes); err != nil {
+			return fmt.Errorf("failed to scan user stats: %w", err)
+		}
+		records[userId] = record
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to get user stats: %w", err)
+	}
+
+	matchRows, err := s.DB.QueryContext(ctx,
+		`SELECT user_id, match_id FROM matches WHERE user_id IN (`+placeholders+`) ORDER BY user_id, match_id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get matches: %w", err)
+	}
+	defer matchRows.Close()
+	for matchRows.Next() {
+		var userId, matchId int
+		if err = matchRows.Scan(&userId, &matchId); err != nil {
+			return fmt.Errorf("failed to scan match: %w", err)
+		}
+		if record, ok := records[userId]; ok { // Same as GetMatches, users without stats don't exist
+			record.Matches.MatchList = append(record.Matches.MatchList, matchId)
+			records[userId] = record
+		}
+	}
+	if err = matchRows.Err(); err != nil {
+		return fmt.Errorf("failed to get matches: %w", err)
+	}
+	return nil
+}
+
+// Get the comments a user received, newest first. The cursor is empty for the first page.
+func (s *SqliteClient) GetComments(ctx context.Context, userId, limit int, cursor string) (models.UserComments, error) {
+	var userComments models.UserComments
+	beforeId := int64(math.MaxInt64)
+	if cursor != "" {
+		position, err := decodeCursor(cursor)
+		if err != nil {
+			return userComments, err
+		}
+		if beforeId, err = strconv.ParseInt(position, 10, 64); err != nil {
+			return userComments, ErrInvalidCursor
+		}
+	}
+
+	// Fetch one extra row to find out if there is a next page
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, from_id, comment, direction, created_at FROM comments
+		WHERE user_id = ? AND id < ? ORDER BY id DESC LIMIT ?`,
+		userId, beforeId, limit+1)
+	if err != nil {
+		return userComments, fmt.Errorf("failed to get comments: %w", err)
+	}
+	defer rows.Close()
+	userComments.Comments = make([]models.Comment, 0, limit)
+	var lastId int64
+	for rows.Next() {
+		if len(userComments.Comments) == limit {
+			userComments.NextCursor = encodeCursor(strconv.FormatInt(lastId, 10))
+			break
+		}
+		var comment models.Comment
+		if err = rows.Scan(&lastId, &comment.From, &comment.Comment, &comment.Direction, &comment.CreatedAt); err != nil {
+			return userComments, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		userComments.Comments = append(userComments.Comments, comment)
+	}
+	if err = rows.Err(); err != nil {
+		return userComments, fmt.Errorf("failed to get comments: %w", err)
+	}
+	return userComments, nil
+}
+
+// Close the underlying database file
+func (s *SqliteClient) Close() error {
+	return s.DB.Close()
+}
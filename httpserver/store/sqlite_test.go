@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSqliteClient(t *testing.T) *SqliteClient {
+	client, err := NewSqliteClient(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// Write rows the way the consumer would
+func seedSqlite(t *testing.T, client *SqliteClient, userId, likes, dislikes int, matchIds ...int) {
+	_, err := client.DB.Exec(
+		`INSERT INTO user_stats (user_id, num_likes, num_dislikes) VALUES (?, ?, ?)`, userId, likes, dislikes)
+	require.NoError(t, err)
+	for _, matchId := range matchIds {
+		_, err = client.DB.Exec(`INSERT INTO matches (user_id, match_id) VALUES (?, ?)`, userId, matchId)
+		require.NoError(t, err)
+	}
+}
+
+// Read back user stats and matches
+func TestSqliteGetUser(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+	seedSqlite(t, client, 1234, 2, 1, 5679, 5678)
+
+	found, stats, err := client.GetUserStats(ctx, 1234)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 2, stats.NumLikes)
+	assert.Equal(t, 1, stats.NumDislikes)
+
+	found, matches, err := client.GetMatches(ctx, 1234)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []int{5678, 5679}, matches.MatchList)
+}
+
+// Read many users at once, leaving out users who don't exist
+func TestSqliteBatchGetUsers(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+	seedSqlite(t, client, 1234, 1, 0, 5678)
+	seedSqlite(t, client, 5678, 1, 1, 1234)
+
+	records, err := client.BatchGetUsers(ctx, []int{1234, 5678, 4321})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, 1, records[1234].Stats.NumLikes)
+	assert.Equal(t, []int{5678}, records[1234].Matches.MatchList)
+	assert.Equal(t, 1, records[5678].Stats.NumDislikes)
+	assert.Equal(t, []int{1234}, records[5678].Matches.MatchList)
+}
+
+// Page through the comment inbox newest first
+func TestSqliteGetComments(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+	for _, comment := range []string{"first", "second", "third"} {
+		_, err := client.DB.Exec(
+			`INSERT INTO comments (user_id, from_id, comment, direction, created_at) VALUES (?, ?, ?, ?, ?)`,
+			5678, 1234, comment, "right", "2023-06-18T00:00:00Z")
+		require.NoError(t, err)
+	}
+
+	page, err := client.GetComments(ctx, 5678, 2, "")
+	assert.NoError(t, err)
+	assert.Len(t, page.Comments, 2)
+	assert.Equal(t, "third", page.Comments[0].Comment)
+	assert.Equal(t, "second", page.Comments[1].Comment)
+	assert.NotEmpty(t, page.NextCursor)
+
+	page, err = client.GetComments(ctx, 5678, 2, page.NextCursor)
+	assert.NoError(t, err)
+	assert.Len(t, page.Comments, 1)
+	assert.Equal(t, "first", page.Comments[0].Comment)
+	assert.Equal(t, 1234, page.Comments[0].From)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = client.GetComments(ctx, 5678, 2, "not a cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// Unknown users are not found but life goes on
+func TestSqliteNotFound(t *testing.T) {
+	ctx := context.Background()
+	client := newTestSqliteClient(t)
+
+	found, _, err := client.GetUserStats(ctx, 1234)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	found, _, err = client.GetMatches(ctx, 1234)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+)
+
+// Read side of the database. The consumer owns every write.
+//
+//go:generate mockery --name=Store --filename=mock_store.go
+type Store interface {
+	GetUserStats(ctx context.Context, userId int) (bool, models.UserStats, error)
+	GetMatches(ctx context.Context, userId int) (bool, models.UserMatches, error)
+	GetComments(ctx context.Context, userId, limit int, cursor string) (models.UserComments, error)
+	BatchGetUsers(ctx context.Context, userIds []int) (map[int]models.UserRecord, error)
+}
+
+// Returned when a pagination cursor wasn't issued by this store
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// How to connect to the database
+type Options struct {
+	Backend     string       // dynamodb, sqlite, or postgres, defaults to dynamodb
+	SqlitePath  string       // SQLite database file
+	PostgresURL string       // PostgreSQL connection string
+	Region      string       // AWS region of the DynamoDB tables
+	Router      shard.Router // DynamoDB shard layout
+}
+
+// Create a new Store for the backend in opts. Defaults to DynamoDB.
+func NewStore(opts Options) (Store, error) {
+	switch opts.Backend {
+	case "", "dynamodb":
+		return NewDatabaseClient(opts.Region, opts.Router)
+	case "sqlite":
+		return NewSqliteClient(opts.SqlitePath)
+	case "postgres":
+		return NewPostgresClient(opts.PostgresURL)
+	default:
+		return nil, fmt.Errorf("unknown DB_BACKEND: %s", opts.Backend)
+	}
+}
+
+// Remove duplicate userIds while keeping the original order
+func uniqueIds(userIds []int) []int {
+	seen := make(map[int]struct{}, len(userIds))
+	unique := make([]int, 0, len(userIds))
+	for _, userId := range userIds {
+		if _, ok := seen[userId]; !ok {
+			seen[userId] = struct{}{}
+			unique = append(unique, userId)
+		}
+	}
+	return unique
+}
+
+// Cut one page out of a full match list, sorted by matchId. The cursor holds the last matchId
+// of the previous page, so pages stay stable when new matches are added. A limit of 0 returns the rest of the list.
+func PageMatches(matches models.UserMatches, limit int, cursor string, descending bool) (models.UserMatches, error) {
+	order := "asc"
+	if descending {
+		order = "desc"
+	}
+	all := make([]int, len(matches.MatchList))
+	copy(all, matches.MatchList)
+	if descending {
+		sort.Sort(sort.Reverse(sort.IntSlice(all)))
+	} else {
+		sort.Ints(all)
+	}
+
+	start := 0
+	if cursor != "" {
+		position, err := decodeCursor(cursor)
+		if err != nil {
+			return models.UserMatches{}, err
+		}
+		cursorOrder, lastId, ok := strings.Cut(position, ":")
+		after, err := strconv.Atoi(lastId)
+		if !ok || err != nil || cursorOrder != order {
+			return models.UserMatches{}, ErrInvalidCursor // Cursors can't switch order
+		}
+		start = sort.Search(len(all), func(i int) bool {
+			if descending {
+				return all[i] < after
+			}
+			return all[i] > after
+		})
+	}
+
+	page := models.UserMatches{
+		MatchList: all[start:],
+		Count:     len(all),
+	}
+	if limit > 0 && len(page.MatchList) > limit {
+		page.MatchList = page.MatchList[:limit]
+		page.NextCursor = encodeCursor(fmt.Sprintf("%s:%d", order, page.MatchList[limit-1]))
+	}
+	return page, nil
+}
+
+// Encode a store specific position into an opaque pagination cursor
+func encodeCursor(position string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position))
+}
+
+// Decode a pagination cursor back into a store specific position
+func decodeCursor(cursor string) (string, error) {
+	position, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(position) == 0 {
+		return "", ErrInvalidCursor
+	}
+	return string(position), nil
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageMatches(t *testing.T) {
+	matches := models.UserMatches{MatchList: []int{5, 1, 4, 2, 3}}
+
+	tests := []struct {
+		name       string
+		descending bool
+		want       [][]int
+	}{
+		{name: "ascending", want: [][]int{{1, 2}, {3, 4}, {5}}},
+		{name: "descending", descending: true, want: [][]int{{5, 4}, {3, 2}, {1}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cursor := ""
+			for i, want := range tc.want {
+				page, err := PageMatches(matches, 2, cursor, tc.descending)
+				assert.NoError(t, err)
+				assert.Equal(t, want, page.MatchList)
+				assert.Equal(t, 5, page.Count)
+				assert.Equal(t, i == len(tc.want)-1, page.NextCursor == "")
+				cursor = page.NextCursor
+			}
+		})
+	}
+}
+
+// A match added between pages doesn't shift the next page
+func TestPageMatchesStable(t *testing.T) {
+	page, err := PageMatches(models.UserMatches{MatchList: []int{10, 20, 30}}, 2, "", false)
+	assert.NoError(t, err)
+
+	page, err = PageMatches(models.UserMatches{MatchList: []int{5, 10, 20, 30}}, 2, page.NextCursor, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{30}, page.MatchList)
+	assert.Equal(t, 4, page.Count)
+}
+
+func TestPageMatchesInvalidCursor(t *testing.T) {
+	matches := models.UserMatches{MatchList: []int{1, 2, 3}}
+	page, _ := PageMatches(matches, 1, "", false)
+
+	_, err := PageMatches(matches, 1, page.NextCursor, true) // can't switch order
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = PageMatches(matches, 1, "garbage!", false)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,285 @@
+// Package config loads a service's settings into a struct. Every field can be set, from lowest to highest
+// precedence, by its default, a YAML config file, an env variable, and a command line flag.
+//
+//	type Config struct {
+//		Port    string        `yaml:"port" env:"PORT" default:"80" usage:"port to listen on"`
+//		Timeout time.Duration `yaml:"timeout" env:"TIMEOUT" default:"5s" required:"true"`
+//		Kind    string        `yaml:"kind" env:"KIND" default:"fanout" oneof:"fanout,topic"`
+//	}
+//
+// The flag name is the yaml key with dashes instead of underscores. Supported field types are string, bool, int,
+// int64, float64, time.Duration, and []string, which is comma separated in env variables and flags.
+// Embedded structs are flattened, so services can share groups of settings like RabbitMQ.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The env variable and flag that point to the YAML config file
+const (
+	FileEnv  = "CONFIG_FILE"
+	FileFlag = "config"
+)
+
+// Implemented by configs that need more validation than required and oneof
+type Validator interface {
+	Validate() error
+}
+
+// A setting of the config struct
+type field struct {
+	value    reflect.Value
+	key      string // yaml key
+	env      string
+	flag     string
+	def      string
+	usage    string
+	required bool
+	oneof    []string
+}
+
+// Load cfg, a pointer to a struct, for the service called name. Args are the command line arguments without
+// the program name. Returns flag.ErrHelp if the args asked for help.
+func Load(cfg interface{}, name string, args []string) error {
+	ptr := reflect.ValueOf(cfg)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Struct {
+		return errors.New("config must be a pointer to a struct")
+	}
+	fields, err := collectFields(ptr.Elem())
+	if err != nil {
+		return err
+	}
+
+	// Flags are parsed first to find the config file, but only applied last
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	file := flags.String(FileFlag, os.Getenv(FileEnv), "YAML config file (env "+FileEnv+")")
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		isBool := f.value.Kind() == reflect.Bool
+		flags.Var(&recorder{name: f.flag, values: flagValues, isBool: isBool}, f.flag, f.help())
+	}
+	if err = flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			flags.SetOutput(os.Stderr)
+			flags.Usage()
+		}
+		return err
+	}
+
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err = set(f.value, f.def); err != nil {
+			return fmt.Errorf("invalid default for %s: %w", f.key, err)
+		}
+	}
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err = yaml.Unmarshal(data, cfg); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", *file, err)
+		}
+	}
+	for _, f := range fields {
+		if env := os.Getenv(f.env); f.env != "" && env != "" { // Empty env variables count as unset
+			if err = set(f.value, env); err != nil {
+				return fmt.Errorf("invalid %s: %w", f.env, err)
+			}
+		}
+	}
+	for _, f := range fields {
+		if raw, ok := flagValues[f.flag]; ok {
+			if err = set(f.value, raw); err != nil {
+				return fmt.Errorf("invalid -%s: %w", f.flag, err)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if err = f.validate(); err != nil {
+			return err
+		}
+	}
+	if validator, ok := cfg.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// Internal function that lists the settings of a struct, including the ones in embedded structs
+func collectFields(v reflect.Value) ([]field, error) {
+	var fields []field
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			embedded, err := collectFields(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		key, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" || !sf.IsExported() {
+			continue
+		}
+		f := field{
+			value:    v.Field(i),
+			key:      key,
+			env:      sf.Tag.Get("env"),
+			flag:     strings.ReplaceAll(key, "_", "-"),
+			def:      sf.Tag.Get("default"),
+			usage:    sf.Tag.Get("usage"),
+			required: sf.Tag.Get("required") == "true",
+		}
+		if oneof := sf.Tag.Get("oneof"); oneof != "" {
+			f.oneof = strings.Split(oneof, ",")
+		}
+		if err := set(reflect.New(sf.Type).Elem(), ""); err != nil && !errors.Is(err, errEmpty) {
+			return nil, fmt.Errorf("unsupported config field %s: %w", sf.Name, err)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// Internal method that checks required and oneof
+func (f field) validate() error {
+	if f.required && f.value.IsZero() && f.env == "" {
+		return fmt.Errorf("missing %s (flag -%s)", f.key, f.flag)
+	}
+	if f.required && f.value.IsZero() {
+		return fmt.Errorf("missing %s (env %s, flag -%s)", f.key, f.env, f.flag)
+	}
+	if len(f.oneof) == 0 || f.value.IsZero() {
+		return nil
+	}
+	values := []string{fmt.Sprint(f.value.Interface())}
+	if list, ok := f.value.Interface().([]string); ok {
+		values = list
+	}
+	for _, value := range values {
+		if !contains(f.oneof, value) {
+			return fmt.Errorf("invalid %s: %s, must be one of %s", f.key, value, strings.Join(f.oneof, ", "))
+		}
+	}
+	return nil
+}
+
+// Internal method that describes a setting in the flag usage
+func (f field) help() string {
+	help := f.usage
+	if f.env != "" {
+		help += fmt.Sprintf(" (env %s)", f.env)
+	}
+	if f.def != "" {
+		help += fmt.Sprintf(" (default %s)", f.def)
+	}
+	return strings.TrimSpace(help)
+}
+
+var errEmpty = errors.New("empty value")
+
+// Internal function that parses raw into a setting
+func set(v reflect.Value, raw string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		if raw == "" {
+			return errEmpty
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			return errEmpty
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		if raw == "" {
+			return errEmpty
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		if raw == "" {
+			return errEmpty
+		}
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// A flag.Value that remembers the raw value so it can be applied after the config file and env variables
+type recorder struct {
+	name   string
+	values map[string]string
+	isBool bool // Bool flags don't need a value
+}
+
+func (r *recorder) String() string {
+	return ""
+}
+
+func (r *recorder) Set(raw string) error {
+	r.values[r.name] = raw
+	return nil
+}
+
+func (r *recorder) IsBoolFlag() bool {
+	return r.isBool
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
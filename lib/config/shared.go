@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+)
+
+// Logging settings shared by every service
+type Logging struct {
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL" default:"info" oneof:"trace,debug,info,warn,error,fatal,panic" usage:"log level"`
+}
+
+// Set the log level of the logger
+func (l Logging) SetLogLevel() error {
+	return logger.SetLevel(l.LogLevel)
+}
+
+// RabbitMQ settings shared by every service that talks to the broker. With a fanout exchange every consumer gets
+// every swipe. With topic, consumers only get the routing keys they bind to.
+type RabbitMQ struct {
+	RabbitHost     string `yaml:"rabbitmq_host" env:"RABBITMQ_HOST" required:"true" usage:"RabbitMQ host"`
+	RabbitPort     int    `yaml:"rabbitmq_port" env:"RABBITMQ_PORT" default:"5672" usage:"RabbitMQ port"`
+	RabbitUser     string `yaml:"rabbitmq_user" env:"RABBITMQ_USER" default:"guest" usage:"RabbitMQ user"`
+	RabbitPassword string `yaml:"rabbitmq_password" env:"RABBITMQ_PASSWORD" default:"guest" usage:"RabbitMQ password"`
+	ExchangeKind   string `yaml:"exchange_kind" env:"SWIPES_EXCHANGE_KIND" default:"fanout" oneof:"fanout,topic" usage:"kind of the swipes exchange, the httpserver and consumer must match"`
+}
+
+// The amqp url of the broker
+func (r RabbitMQ) RabbitURL() string {
+	u := url.URL{
+		Scheme: "amqp",
+		User:   url.UserPassword(r.RabbitUser, r.RabbitPassword),
+		Host:   net.JoinHostPort(r.RabbitHost, strconv.Itoa(r.RabbitPort)),
+	}
+	return u.String()
+}
+
+// Don't log the password
+func (r RabbitMQ) String() string {
+	return fmt.Sprintf("amqp://%s@%s:%d", r.RabbitUser, r.RabbitHost, r.RabbitPort)
+}
+
+// AWS settings shared by the services that use DynamoDB
+type AWS struct {
+	AWSRegion string `yaml:"aws_region" env:"AWS_REGION" default:"us-east-2" usage:"AWS region of the DynamoDB tables"`
+}
+
+// Metrics settings shared by the httpserver and consumer, see the metrics package
+type Metrics struct {
+	MetricsBackend  string        `yaml:"metrics_backend" env:"METRICS_BACKEND" default:"axiom" oneof:"axiom,prometheus" usage:"serve metrics to Prometheus at /metrics or send them to Axiom"`
+	MetricsInterval time.Duration `yaml:"metrics_interval" env:"METRICS_INTERVAL" default:"5s" usage:"how often metrics are sent to Axiom"`
+	AxiomToken      string        `yaml:"axiom_api_token" env:"AXIOM_API_TOKEN" usage:"Axiom API token"`
+	AxiomDataset    string        `yaml:"axiom_dataset" env:"AXIOM_DATASET" usage:"Axiom dataset name"`
+}
+
+// Check that Axiom has its credentials and the interval is positive
+func (m Metrics) ValidateMetrics() error {
+	if m.MetricsBackend == "axiom" && (m.AxiomToken == "" || m.AxiomDataset == "") {
+		return errors.New("metrics_backend axiom needs an axiom_api_token and axiom_dataset")
+	}
+	if m.MetricsInterval <= 0 {
+		return errors.New("metrics_interval must be positive")
+	}
+	return nil
+}
+
+// Tracing settings shared by the httpserver and consumer, see tracing.Setup
+type Tracing struct {
+	TraceExporter string `yaml:"trace_exporter" env:"TRACE_EXPORTER" default:"none" oneof:"none,stdout,otlp" usage:"where OpenTelemetry spans go"`
+	TraceEndpoint string `yaml:"trace_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" usage:"OTLP/HTTP collector url, eg. http://localhost:4318"`
+}
@@ -0,0 +1,203 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/tracing"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// The DynamoDB calls the shared user row helpers need. The httpserver and consumer clients both satisfy it.
+type Client interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// The primary key of a user row
+func UserKey(userId int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"userId": &types.AttributeValueMemberN{Value: strconv.Itoa(userId)},
+	}
+}
+
+// Get the entire row of a user from one table. Returns nil if the user isn't there.
+func GetItem(ctx context.Context, client Client, tableName string, userId int) (*models.DynamoUserStats, error) {
+	_, span := tracing.StartDynamoDB(ctx, "GetItem", tableName)
+	defer span.End()
+	resp, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &tableName,
+		Key:       UserKey(userId),
+	})
+	tracing.RecordError(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if resp.Item == nil { // Item not found
+		return nil, nil
+	}
+	dynamoItem := &models.DynamoUserStats{}
+	if err = attributevalue.UnmarshalMap(resp.Item, &dynamoItem); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal item: %w", err)
+	}
+	return dynamoItem, nil
+}
+
+// Get the entire row of a user from the table router puts them in. While a user is being moved to
+// another shard, the old table is read before the new one and both rows are merged. A move adds the row
+// to the new table before deleting it from the old one, so a move between the two reads can't hide the user.
+// At worst that move shows up in both rows and is counted twice for one read.
+func GetUserItem(ctx context.Context, client Client, router shard.Router, userId int) (*models.DynamoUserStats, error) {
+	var oldItem *models.DynamoUserStats
+	if migrating, ok := router.(*shard.MigratingRouter); ok {
+		if previous, moving := migrating.PreviousTable(userId); moving {
+			var err error
+			if oldItem, err = GetItem(ctx, client, previous, userId); err != nil {
+				return nil, err
+			}
+		}
+	}
+	item, err := GetItem(ctx, client, router.Table(userId), userId)
+	if err != nil {
+		return nil, err
+	}
+	return MergeItems(item, oldItem), nil
+}
+
+// Merge the rows of a user that lives in two tables. Either row may be nil.
+func MergeItems(a, b *models.DynamoUserStats) *models.DynamoUserStats {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return &models.DynamoUserStats{
+		UserId:      a.UserId,
+		NumLikes:    a.NumLikes + b.NumLikes,
+		NumDislikes: a.NumDislikes + b.NumDislikes,
+		LikedList:   union(a.LikedList, b.LikedList),
+		MatchList:   union(a.MatchList, b.MatchList),
+	}
+}
+
+// Add each user to the other's matchList, but only if the swipee already liked the user.
+// The user's like must be written before this check, so two concurrent mutual swipes can't both miss each other.
+// Adding to a number set is idempotent, so a retry can't add a match twice.
+func AddMatchIfMutual(ctx context.Context, client Client, router shard.Router, userId, swipee int) error {
+	if userId == swipee {
+		return nil // Liking yourself is not a match
+	}
+	swipeeTable := router.Table(swipee)
+	_, span := tracing.StartDynamoDB(ctx, "TransactWriteItems", swipeeTable)
+	_, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName:           aws.String(swipeeTable),
+					Key:                 UserKey(swipee),
+					ConditionExpression: aws.String("contains(likedList, :userId)"),
+					UpdateExpression:    aws.String("ADD matchList :match"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":userId": &types.AttributeValueMemberN{Value: strconv.Itoa(userId)},
+						":match":  &types.AttributeValueMemberNS{Value: []string{strconv.Itoa(userId)}},
+					},
+				},
+			},
+			{
+				Update: addMatchUpdate(router.Table(userId), userId, swipee),
+			},
+		},
+	})
+	span.End()
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return addMatchIfMovingLike(ctx, client, router, userId, swipee) // Not a mutual match (yet)
+			}
+		}
+	}
+	tracing.RecordError(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to add match: %w", err)
+	}
+	return nil
+}
+
+// While the swipee is being moved to another shard, their like may still be in the old table.
+// The old row no longer changes, so no condition is needed once the like is found there.
+func addMatchIfMovingLike(ctx context.Context, client Client, router shard.Router, userId, swipee int) error {
+	migrating, ok := router.(*shard.MigratingRouter)
+	if !ok {
+		return nil
+	}
+	previous, moving := migrating.PreviousTable(swipee)
+	if !moving {
+		return nil
+	}
+	oldItem, err := GetItem(ctx, client, previous, swipee)
+	if err != nil || oldItem == nil {
+		return err
+	}
+	liked := false
+	for _, likedId := range oldItem.LikedList {
+		liked = liked || likedId == userId
+	}
+	if !liked {
+		return nil
+	}
+	_, span := tracing.StartDynamoDB(ctx, "TransactWriteItems", router.Table(swipee))
+	_, err = client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Update: addMatchUpdate(router.Table(swipee), swipee, userId)},
+			{Update: addMatchUpdate(router.Table(userId), userId, swipee)},
+		},
+	})
+	tracing.RecordError(span, err)
+	span.End()
+	if err != nil {
+		return fmt.Errorf("failed to add match: %w", err)
+	}
+	return nil
+}
+
+// Add matchId to a user's matchList
+func addMatchUpdate(tableName string, userId, matchId int) *types.Update {
+	return &types.Update{
+		TableName:        aws.String(tableName),
+		Key:              UserKey(userId),
+		UpdateExpression: aws.String("ADD matchList :match"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":match": &types.AttributeValueMemberNS{Value: []string{strconv.Itoa(matchId)}},
+		},
+	}
+}
+
+// The sorted union of two number sets
+func union(a, b []int) []int {
+	set := make(map[int]struct{}, len(a)+len(b))
+	for _, n := range a {
+		set[n] = struct{}{}
+	}
+	for _, n := range b {
+		set[n] = struct{}{}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	merged := make([]int, 0, len(set))
+	for n := range set {
+		merged = append(merged, n)
+	}
+	sort.Ints(merged)
+	return merged
+}
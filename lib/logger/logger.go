@@ -31,6 +31,16 @@ func setupLogger() {
 	zlog = zerolog.New(os.Stdout).With().Timestamp().Logger()
 }
 
+// Change the log level, like debug or warn, after the config is loaded
+func SetLevel(logLevel string) error {
+	level, err := zerolog.ParseLevel(logLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	zerolog.SetGlobalLevel(level)
+	return nil
+}
+
 // Get the logger singleton
 func GetLogger() zerolog.Logger {
 	once.Do(setupLogger)
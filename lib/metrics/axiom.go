@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/axiomhq/axiom-go/axiom"
+)
+
+// Sends events to an Axiom dataset. Services embed it in their AxiomMetrics.
+type Axiom struct {
+	client      *axiom.Client
+	DatasetName string
+}
+
+// Create a new Axiom client for the dataset
+func NewAxiom(apiToken, datasetName string) (*Axiom, error) {
+	if apiToken == "" || datasetName == "" {
+		return nil, errors.New("missing the Axiom API token or dataset")
+	}
+	client, err := axiom.NewClient(
+		axiom.SetAPITokenConfig(apiToken),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Axiom{
+		client:      client,
+		DatasetName: datasetName,
+	}, nil
+}
+
+// Ingest the events into the dataset
+func (a *Axiom) Ingest(ctx context.Context, events ...axiom.Event) error {
+	if _, err := a.client.IngestEvents(ctx, a.DatasetName, events); err != nil {
+		return fmt.Errorf("failed to ingest metrics: %w", err)
+	}
+	return nil
+}
+
+// Convert a model to an Axiom event keyed by its json tags
+func ToEvent(v any) (axiom.Event, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+	var event axiom.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metrics: %w", err)
+	}
+	return event, nil
+}
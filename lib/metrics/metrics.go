@@ -0,0 +1,39 @@
+// Package metrics has the metrics backends shared by the httpserver and consumer. Each service extends them with
+// its own counters, and either sends them to Axiom every interval or serves them to a Prometheus scraper.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/logger"
+)
+
+var zlog = logger.GetLogger()
+
+// What every service's metrics client can do
+type Metrics interface {
+	SendMetrics() error
+}
+
+// Implemented by Metrics that are scraped instead of sent, like Prometheus
+type Exporter interface {
+	Handler() http.Handler // Serves the metrics at /metrics
+}
+
+// Send the metrics every interval until the context is cancelled
+func Run(ctx context.Context, m Metrics, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.SendMetrics(); err != nil {
+				zlog.Error().Err(err).Msg("unable to send metrics")
+			}
+		}
+	}
+}
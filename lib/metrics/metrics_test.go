@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToEvent(t *testing.T) {
+	event, err := ToEvent(struct {
+		ServerId string `json:"serverId"`
+		Count    uint64 `json:"count"`
+	}{ServerId: "s1", Count: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "s1", event["serverId"])
+	assert.Equal(t, 3.0, event["count"])
+}
+
+// The service's collectors are served next to the runtime metrics
+func TestPrometheusHandler(t *testing.T) {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "twinder_test_total", Help: "Test counter"})
+	p := NewPrometheus(counter)
+	counter.Add(2)
+	assert.NoError(t, p.SendMetrics())
+
+	rr := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "twinder_test_total 2")
+	assert.Contains(t, string(body), "go_goroutines")
+}
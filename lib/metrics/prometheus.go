@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// A Prometheus registry with the Go runtime and process metrics. Services embed it in their PrometheusMetrics.
+type Prometheus struct {
+	registry *prometheus.Registry
+}
+
+// Create a new Prometheus registry with the service's collectors
+func NewPrometheus(cs ...prometheus.Collector) *Prometheus {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(cs...)
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return &Prometheus{registry: registry}
+}
+
+// Nothing to send, Prometheus scrapes the metrics
+func (p *Prometheus) SendMetrics() error {
+	return nil
+}
+
+// Serve the metrics in the Prometheus text format
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
@@ -4,8 +4,19 @@ package models
 
 // The DynamoDB table schema
 type DynamoUserStats struct {
-	UserId      int   `dynamodbav:"userId"`
-	NumLikes    int   `dynamodbav:"numLikes"`
-	NumDislikes int   `dynamodbav:"numDislikes"`
-	MatchList   []int `dynamodbav:"matchList"`
+	UserId      int   `dynamodbav:"userId" json:"userId"`
+	NumLikes    int   `dynamodbav:"numLikes" json:"numLikes"`
+	NumDislikes int   `dynamodbav:"numDislikes" json:"numDislikes"`
+	LikedList   []int `dynamodbav:"likedList" json:"likedList"` // Everyone this user swiped right on
+	MatchList   []int `dynamodbav:"matchList" json:"matchList"` // Only mutual likes. Rows written before likedList existed hold every right swipe here until migrate -backfill runs.
+}
+
+// The DynamoDB comments table schema. Partitioned by the user who received the comment.
+type DynamoComment struct {
+	UserId    int    `dynamodbav:"userId"`
+	CommentId string `dynamodbav:"commentId"` // Sort key. Starts with the unix nano timestamp so it sorts by time.
+	From      int    `dynamodbav:"from"`
+	Comment   string `dynamodbav:"comment"`
+	Direction string `dynamodbav:"direction"`
+	CreatedAt string `dynamodbav:"createdAt"`
 }
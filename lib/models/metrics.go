@@ -4,14 +4,41 @@ package models
 
 // How many database writes per unit of time
 type ConsumerWriteThroughput struct {
-	Time       string `json:"_time"`
-	ConsumerId string `json:"consumerId"`
-	Throughput uint64 `json:"throughput"`
+	Time              string  `json:"_time"`
+	ConsumerId        string  `json:"consumerId"`
+	Throughput        uint64  `json:"throughput"`
+	WritesPerSec      float64 `json:"writesPerSec"`
+	WriteLatencyAvgMs float64 `json:"writeLatencyAvgMs"`
+	WriteLatencyMaxMs int64   `json:"writeLatencyMaxMs"`
+	Errors            uint64  `json:"errors"`    // Failed writes, including throttled ones
+	Throttles         uint64  `json:"throttles"` // Writes the store throttled
+	InFlight          int     `json:"inFlight"`  // Deliveries being handled right now
 }
 
-// How many server requests handled per unit of time
+// How many server requests handled per unit of time, and how the cache and RabbitMQ kept up
 type ServerThroughput struct {
-	Time       string `json:"_time"`
-	ServerId   string `json:"serverId"`
-	Throughput uint64 `json:"throughput"`
+	Time                string  `json:"_time"`
+	ServerId            string  `json:"serverId"`   // Was ServerId before the per-route events
+	Throughput          uint64  `json:"throughput"` // Was Throughput before the per-route events
+	CacheHits           uint64  `json:"cacheHits"`
+	CacheMisses         uint64  `json:"cacheMisses"`
+	PublishLatencyAvgMs float64 `json:"publishLatencyAvgMs"`
+	PublishErrors       uint64  `json:"publishErrors"`
+	ConfirmLatencyAvgMs float64 `json:"confirmLatencyAvgMs"`
+	ConfirmLatencyMaxMs int64   `json:"confirmLatencyMaxMs"`
+	SpoolDepth          int     `json:"spoolDepth"` // Swipes waiting on disk for RabbitMQ to come back
+}
+
+// Count and latency of one route, method, and status code per unit of time
+type ServerRouteStats struct {
+	Time         string  `json:"_time"`
+	ServerId     string  `json:"serverId"`
+	Route        string  `json:"route"` // chi route pattern, eg. /stats/{userId}/
+	Method       string  `json:"method"`
+	Status       int     `json:"status"`
+	Count        uint64  `json:"count"`
+	LatencyP50Ms float64 `json:"latencyP50Ms"`
+	LatencyP95Ms float64 `json:"latencyP95Ms"`
+	LatencyP99Ms float64 `json:"latencyP99Ms"`
+	LatencyMaxMs float64 `json:"latencyMaxMs"`
 }
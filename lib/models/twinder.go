@@ -6,6 +6,38 @@ type SwipeRequest struct {
 	Swipee    string `json:"swipee"`
 	Comment   string `json:"comment"`
 	Direction string `json:"direction,omitempty"`
+	SwipeId   string `json:"swipeId,omitempty"` // Assigned by the httpserver so the consumer can skip redeliveries
+}
+
+// Server side result of one swipe in a batch, in the same order as the request
+type SwipeResult struct {
+	Status  int    `json:"status"`            // The HTTP status the swipe would have gotten on its own
+	SwipeId string `json:"swipeId,omitempty"` // Only set if the swipe was accepted
+	Message string `json:"message,omitempty"` // Why the swipe was rejected
+}
+
+// Most swipes in one POST /swipes/batch, unless the httpserver sets MAX_SWIPE_BATCH
+const DefaultMaxSwipeBatch = 500
+
+// Server side response to a batch of swipes
+type BatchSwipeResponse struct {
+	Accepted int           `json:"accepted"`
+	Results  []SwipeResult `json:"results"`
+}
+
+// Server side reason one line of a swipe stream was rejected
+type LineError struct {
+	Line    int    `json:"line"` // Starts at 1
+	Message string `json:"message"`
+}
+
+// Server side summary of a swipe stream
+type StreamSwipeSummary struct {
+	Lines      int         `json:"lines"` // Non blank lines read
+	Accepted   int         `json:"accepted"`
+	Rejected   int         `json:"rejected"`
+	Rejections []LineError `json:"rejections"`      // Only the first few rejections are listed
+	Error      string      `json:"error,omitempty"` // Set if the stream stopped before the end of the body
 }
 
 // Server side user stats
@@ -14,9 +46,65 @@ type UserStats struct {
 	NumDislikes int `json:"numDislikes"`
 }
 
-// Server side user matches
+// Server side user matches. Only users who liked each other are a match.
 type UserMatches struct {
-	MatchList []int `json:"matchList"`
+	MatchList  []int  `json:"matchList"`
+	Count      int    `json:"count"`                // Total number of matches across all pages
+	NextCursor string `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+// Server side stats and matches of one user, read together by bulk lookups
+type UserRecord struct {
+	Stats   UserStats
+	Matches UserMatches
+}
+
+// Client side bulk lookup of many users
+type BatchRequest struct {
+	UserIds []int `json:"userIds"`
+}
+
+// Server side stats of one user in a bulk lookup. Stats is omitted when the user isn't found.
+type UserStatsResult struct {
+	UserId int        `json:"userId"`
+	Found  bool       `json:"found"`
+	Stats  *UserStats `json:"stats,omitempty"`
+}
+
+// Server side matches of one user in a bulk lookup. Matches is omitted when the user isn't found.
+type UserMatchesResult struct {
+	UserId  int          `json:"userId"`
+	Found   bool         `json:"found"`
+	Matches *UserMatches `json:"matches,omitempty"`
+}
+
+// Server side results of a bulk stats lookup, in the same order as the requested userIds
+type BatchUserStats struct {
+	Results []UserStatsResult `json:"results"`
+}
+
+// Server side results of a bulk matches lookup, in the same order as the requested userIds
+type BatchUserMatches struct {
+	Results []UserMatchesResult `json:"results"`
+}
+
+// A comment left on a swipe
+type Comment struct {
+	From      int    `json:"from"`
+	Comment   string `json:"comment"`
+	Direction string `json:"direction"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Server side comments received by a user, newest first
+type UserComments struct {
+	Comments   []Comment `json:"comments"`
+	NextCursor string    `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+// Published by the consumer after a write so the httpserver can drop stale cache entries
+type CacheInvalidation struct {
+	UserIds []int `json:"userIds"`
 }
 
 // Server side error
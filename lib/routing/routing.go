@@ -0,0 +1,36 @@
+package routing
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+)
+
+// The exchange every swipe is published to
+const Exchange = "swipes"
+
+// The routing key of a swipe, like swipe.right.SwipeData3. The shard is the table the swiper lives in,
+// since that's the row the swipe updates. Fanout exchanges ignore it.
+func Key(router shard.Router, sr models.SwipeRequest) string {
+	swiper, _ := strconv.Atoi(sr.Swiper)
+	return fmt.Sprintf("swipe.%s.%s", sr.Direction, router.Table(swiper))
+}
+
+// The binding keys for a consumer that owns the given directions and shards. An empty list means all of them.
+func Bindings(directions, tables []string) []string {
+	if len(directions) == 0 {
+		directions = []string{"*"}
+	}
+	if len(tables) == 0 {
+		tables = []string{"*"}
+	}
+	bindings := make([]string, 0, len(directions)*len(tables))
+	for _, direction := range directions {
+		for _, table := range tables {
+			bindings = append(bindings, fmt.Sprintf("swipe.%s.%s", direction, table))
+		}
+	}
+	return bindings
+}
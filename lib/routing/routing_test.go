@@ -0,0 +1,46 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/DennisPing/cs6650-twinder-a3/lib/models"
+	"github.com/DennisPing/cs6650-twinder-a3/lib/shard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	router, err := shard.NewRangeRouter(shard.DefaultRanges)
+	require.NoError(t, err)
+
+	assert.Equal(t, "swipe.right.SwipeData3", Key(router, models.SwipeRequest{Swiper: "2500", Direction: "right"}))
+	assert.Equal(t, "swipe.left.SwipeData1", Key(router, models.SwipeRequest{Swiper: "1", Direction: "left"}))
+}
+
+func TestBindings(t *testing.T) {
+	tests := []struct {
+		name       string
+		directions []string
+		tables     []string
+		want       []string
+	}{
+		{name: "everything", want: []string{"swipe.*.*"}},
+		{name: "one direction", directions: []string{"right"}, want: []string{"swipe.right.*"}},
+		{
+			name:   "some shards",
+			tables: []string{"SwipeData1", "SwipeData2"},
+			want:   []string{"swipe.*.SwipeData1", "swipe.*.SwipeData2"},
+		},
+		{
+			name:       "both",
+			directions: []string{"left", "right"},
+			tables:     []string{"SwipeData1"},
+			want:       []string{"swipe.left.SwipeData1", "swipe.right.SwipeData1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Bindings(tc.directions, tc.tables))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package schema
+
+// The SQLite schema. The httpserver and consumer share the same file, so both create it from here.
+const Sqlite = `
+CREATE TABLE IF NOT EXISTS user_stats (
+	user_id      INTEGER PRIMARY KEY,
+	num_likes    INTEGER NOT NULL DEFAULT 0,
+	num_dislikes INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS likes (
+	user_id  INTEGER NOT NULL,
+	liked_id INTEGER NOT NULL,
+	PRIMARY KEY (user_id, liked_id)
+);
+CREATE TABLE IF NOT EXISTS matches (
+	user_id  INTEGER NOT NULL,
+	match_id INTEGER NOT NULL,
+	PRIMARY KEY (user_id, match_id)
+);
+CREATE TABLE IF NOT EXISTS comments (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id    INTEGER NOT NULL,
+	from_id    INTEGER NOT NULL,
+	comment    TEXT NOT NULL,
+	direction  TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS comments_user_id ON comments (user_id, id);
+CREATE TABLE IF NOT EXISTS applied_swipes (
+	swipe_id   TEXT PRIMARY KEY,
+	applied_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS applied_swipes_applied_at ON applied_swipes (applied_at);`
+
+// The PostgreSQL schema. Same layout as the SQLite schema.
+const Postgres = `
+CREATE TABLE IF NOT EXISTS user_stats (
+	user_id      INTEGER PRIMARY KEY,
+	num_likes    INTEGER NOT NULL DEFAULT 0,
+	num_dislikes INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS likes (
+	user_id  INTEGER NOT NULL,
+	liked_id INTEGER NOT NULL,
+	PRIMARY KEY (user_id, liked_id)
+);
+CREATE TABLE IF NOT EXISTS matches (
+	user_id  INTEGER NOT NULL,
+	match_id INTEGER NOT NULL,
+	PRIMARY KEY (user_id, match_id)
+);
+CREATE TABLE IF NOT EXISTS comments (
+	id         BIGSERIAL PRIMARY KEY,
+	user_id    INTEGER NOT NULL,
+	from_id    INTEGER NOT NULL,
+	comment    TEXT NOT NULL,
+	direction  TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS comments_user_id ON comments (user_id, id);
+CREATE TABLE IF NOT EXISTS applied_swipes (
+	swipe_id   TEXT PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS applied_swipes_applied_at ON applied_swipes (applied_at);`
@@ -0,0 +1,207 @@
+package shard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Picks the DynamoDB table a user lives in. The httpserver and consumer must use the same config.
+type Router interface {
+	Table(userId int) string
+	Tables() []string
+}
+
+// A range of userIds that live in one table. The last range has no upper bound.
+type Range struct {
+	Max   int    `json:"max"` // Inclusive
+	Table string `json:"table"`
+}
+
+// The shard config file
+type Config struct {
+	Strategy string   `json:"strategy"` // "range" or "hash"
+	Ranges   []Range  `json:"ranges"`   // For the range strategy
+	Tables   []string `json:"tables"`   // For the hash strategy
+	Replicas int      `json:"replicas"` // Virtual nodes per table for the hash strategy
+	Previous *Config  `json:"previous"` // The layout being migrated away from, if any
+}
+
+// The original 5 shards of 1000 users each. Everything above 4000 lands in SwipeData5.
+var DefaultRanges = []Range{
+	{Max: 1000, Table: "SwipeData1"},
+	{Max: 2000, Table: "SwipeData2"},
+	{Max: 3000, Table: "SwipeData3"},
+	{Max: 4000, Table: "SwipeData4"},
+	{Table: "SwipeData5"},
+}
+
+const defaultReplicas = 100
+
+// Create a new Router from the shard config file at path. An empty path means DefaultRanges.
+func NewRouterFromFile(path string) (Router, error) {
+	if path == "" {
+		return NewRangeRouter(DefaultRanges)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRouter(cfg)
+}
+
+// Read a JSON shard config file
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read shard config: %w", err)
+	}
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse shard config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Create a new Router for the config's strategy. If the config has a previous layout, a *MigratingRouter is returned.
+func NewRouter(cfg Config) (Router, error) {
+	if cfg.Previous != nil {
+		current := cfg
+		current.Previous = nil
+		router, err := NewRouter(current)
+		if err != nil {
+			return nil, err
+		}
+		previous, err := NewRouter(*cfg.Previous)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous shard config: %w", err)
+		}
+		return &MigratingRouter{
+			Router:   router,
+			Previous: previous,
+		}, nil
+	}
+	switch cfg.Strategy {
+	case "", "range":
+		return NewRangeRouter(cfg.Ranges)
+	case "hash":
+		return NewHashRouter(cfg.Tables, cfg.Replicas)
+	default:
+		return nil, fmt.Errorf("unknown shard strategy: %s", cfg.Strategy)
+	}
+}
+
+// Routes contiguous userId ranges to tables
+type RangeRouter struct {
+	ranges []Range
+}
+
+// Create a new RangeRouter. Ranges must be in ascending order.
+func NewRangeRouter(ranges []Range) (*RangeRouter, error) {
+	if len(ranges) == 0 {
+		return nil, errors.New("no shard ranges")
+	}
+	for i, r := range ranges {
+		if r.Table == "" {
+			return nil, fmt.Errorf("shard range %d has no table", i)
+		}
+		if i > 0 && i < len(ranges)-1 && r.Max <= ranges[i-1].Max {
+			return nil, fmt.Errorf("shard ranges are not in ascending order at %d", r.Max)
+		}
+	}
+	return &RangeRouter{
+		ranges: ranges,
+	}, nil
+}
+
+// Get the table of a userId
+func (r *RangeRouter) Table(userId int) string {
+	last := len(r.ranges) - 1
+	i := sort.Search(last, func(i int) bool {
+		return userId <= r.ranges[i].Max
+	})
+	return r.ranges[i].Table
+}
+
+// Get every table in range order
+func (r *RangeRouter) Tables() []string {
+	tables := make([]string, 0, len(r.ranges))
+	for _, rng := range r.ranges {
+		tables = append(tables, rng.Table)
+	}
+	return tables
+}
+
+// Routes userIds to tables with consistent hashing, so adding a table only moves about 1/n of the users
+type HashRouter struct {
+	tables []string
+	ring   []uint32          // Sorted virtual node hashes
+	owners map[uint32]string // Virtual node hash -> table
+}
+
+// Create a new HashRouter with replicas virtual nodes per table
+func NewHashRouter(tables []string, replicas int) (*HashRouter, error) {
+	if len(tables) == 0 {
+		return nil, errors.New("no shard tables")
+	}
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+	h := &HashRouter{
+		tables: tables,
+		owners: make(map[uint32]string, len(tables)*replicas),
+	}
+	for _, table := range tables {
+		for i := 0; i < replicas; i++ {
+			node := hash(table + "#" + strconv.Itoa(i))
+			if _, taken := h.owners[node]; taken {
+				continue // Astronomically rare, first table wins
+			}
+			h.owners[node] = table
+			h.ring = append(h.ring, node)
+		}
+	}
+	sort.Slice(h.ring, func(i, j int) bool { return h.ring[i] < h.ring[j] })
+	return h, nil
+}
+
+// Get the table of a userId. The user belongs to the first virtual node clockwise on the ring.
+func (h *HashRouter) Table(userId int) string {
+	key := hash(strconv.Itoa(userId))
+	i := sort.Search(len(h.ring), func(i int) bool {
+		return h.ring[i] >= key
+	})
+	if i == len(h.ring) {
+		i = 0 // Wrap around
+	}
+	return h.owners[h.ring[i]]
+}
+
+// Get every table in config order
+func (h *HashRouter) Tables() []string {
+	return append([]string(nil), h.tables...)
+}
+
+// A Router in the middle of moving users from a previous layout. Writes go to the new table
+// and reads must check both tables until the user has been moved.
+type MigratingRouter struct {
+	Router
+	Previous Router
+}
+
+// Get the table a user lived in before the migration. Returns false if the user doesn't move.
+func (m *MigratingRouter) PreviousTable(userId int) (string, bool) {
+	previous := m.Previous.Table(userId)
+	return previous, previous != m.Router.Table(userId)
+}
+
+// 32-bit FNV-1a
+func hash(key string) uint32 {
+	f := fnv.New32a()
+	f.Write([]byte(key))
+	return f.Sum32()
+}
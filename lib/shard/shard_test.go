@@ -0,0 +1,93 @@
+package shard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The default ranges match the original hard-coded shards
+func TestRangeRouterDefault(t *testing.T) {
+	router, err := NewRangeRouter(DefaultRanges)
+	require.NoError(t, err)
+
+	tests := map[int]string{
+		1:      "SwipeData1",
+		1000:   "SwipeData1",
+		1001:   "SwipeData2",
+		3500:   "SwipeData4",
+		4000:   "SwipeData4",
+		4001:   "SwipeData5",
+		999999: "SwipeData5",
+	}
+	for userId, table := range tests {
+		assert.Equal(t, table, router.Table(userId), "userId %d", userId)
+	}
+}
+
+func TestRangeRouterInvalid(t *testing.T) {
+	_, err := NewRangeRouter(nil)
+	assert.EqualError(t, err, "no shard ranges")
+
+	_, err = NewRangeRouter([]Range{{Max: 2000, Table: "A"}, {Max: 1000, Table: "B"}, {Table: "C"}})
+	assert.EqualError(t, err, "shard ranges are not in ascending order at 1000")
+
+	_, err = NewRangeRouter([]Range{{Max: 1000}, {Table: "B"}})
+	assert.EqualError(t, err, "shard range 0 has no table")
+}
+
+// Adding a table only moves the users that now belong to it
+func TestHashRouterConsistent(t *testing.T) {
+	before, err := NewHashRouter([]string{"A", "B", "C", "D"}, 0)
+	require.NoError(t, err)
+	after, err := NewHashRouter([]string{"A", "B", "C", "D", "E"}, 0)
+	require.NoError(t, err)
+
+	counts := make(map[string]int)
+	for userId := 1; userId <= 10000; userId++ {
+		table := after.Table(userId)
+		counts[table]++
+		if table != "E" {
+			assert.Equal(t, before.Table(userId), table)
+		}
+	}
+	for _, table := range after.Tables() {
+		assert.InDelta(t, 2000, counts[table], 1000, "table %s", table) // roughly balanced
+	}
+}
+
+func TestNewRouterFromConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shards.json")
+	config := `{"strategy": "range", "ranges": [{"max": 5000, "table": "Small"}, {"table": "Big"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o644))
+
+	router, err := NewRouterFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Small", router.Table(5000))
+	assert.Equal(t, "Big", router.Table(5001))
+	assert.Equal(t, []string{"Small", "Big"}, router.Tables())
+
+	_, err = NewRouter(Config{Strategy: "random"})
+	assert.EqualError(t, err, "unknown shard strategy: random")
+}
+
+func TestMigratingRouter(t *testing.T) {
+	router, err := NewRouter(Config{
+		Ranges:   []Range{{Max: 1000, Table: "A"}, {Max: 2000, Table: "B"}, {Table: "C"}},
+		Previous: &Config{Ranges: []Range{{Max: 1000, Table: "A"}, {Table: "B"}}},
+	})
+	require.NoError(t, err)
+	migrating, ok := router.(*MigratingRouter)
+	require.True(t, ok)
+
+	assert.Equal(t, "C", migrating.Table(3000)) // writes go to the new table
+	previous, moving := migrating.PreviousTable(3000)
+	assert.True(t, moving)
+	assert.Equal(t, "B", previous)
+
+	_, moving = migrating.PreviousTable(1500)
+	assert.False(t, moving)
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// A span started from the headers of a message belongs to the trace that published it
+func TestPropagation(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), "test", Options{Exporter: "stdout", Writer: &out})
+	require.NoError(t, err)
+
+	ctx, publish := Start(context.Background(), "publish")
+	headers := map[string]interface{}{"x-retry-count": int32(1)}
+	Inject(ctx, headers)
+	publish.End()
+	assert.Contains(t, headers, "traceparent")
+
+	_, handle := Start(Extract(context.Background(), headers), "handle")
+	RecordError(handle, errors.New("aws died"))
+	handle.End()
+	assert.Equal(t, publish.SpanContext().TraceID(), handle.SpanContext().TraceID())
+
+	require.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, out.String(), `"Name":"publish"`)
+	assert.Contains(t, out.String(), `"Name":"handle"`)
+	assert.Contains(t, out.String(), "aws died")
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+	}{
+		{"none", "none", false},
+		{"empty is none", "", false},
+		{"otlp", "otlp", false}, // Connects lazily
+		{"unknown", "jaeger", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), "test", Options{Exporter: tt.exporter, Endpoint: "http://localhost:4318"})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
+
+// Messages published without a trace context start a new trace
+func TestExtractWithoutHeaders(t *testing.T) {
+	ctx := Extract(context.Background(), nil)
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+}